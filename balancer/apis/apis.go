@@ -0,0 +1,55 @@
+/*
+ *
+ * Copyright 2021 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package apis holds the types that are shared between gRPC's internal
+// connection management and external balancer.Balancer/balancer.Picker
+// implementations.
+//
+// All APIs in this package are experimental.
+package apis
+
+import (
+	"google.golang.org/grpc/attributes"
+	"google.golang.org/grpc/resolver"
+)
+
+// SubConn represents a gRPC sub connection, as exposed to a balancer.Picker.
+//
+// Each SubConn represents one or more addresses gRPC may use to establish a
+// connection, the state of which is reported to the Balancer via
+// Balancer.UpdateSubConnState.
+type SubConn interface {
+	// UpdateAddresses updates the addresses used in this SubConn. gRPC
+	// checks if the currently connected address is still in the new list.
+	// If it's in the list, the connection will be kept. If it's not in the
+	// list, the connection will gracefully close, and a new connection will
+	// be created.
+	UpdateAddresses([]resolver.Address)
+	// Connect starts the connecting for this SubConn.
+	Connect()
+	// Attributes returns the attributes of this SubConn: the Attributes
+	// that were set on the resolver.Address used to create it, merged with
+	// any set via balancer.NewSubConnOptions.Attributes. It returns nil if
+	// none were ever set.
+	//
+	// Picker implementations should use Attributes (keyed off of a
+	// resolver.Address field such as Addr, or a user-defined attribute
+	// key) to select a SubConn, instead of reaching into gRPC's connection
+	// internals.
+	Attributes() *attributes.Attributes
+}
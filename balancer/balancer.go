@@ -28,6 +28,7 @@ import (
 	"net"
 	"strings"
 
+	"google.golang.org/grpc/attributes"
 	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/internal"
@@ -88,6 +89,13 @@ type NewSubConnOptions struct {
 	// HealthCheckEnabled indicates whether health check service should be
 	// enabled on this SubConn
 	HealthCheckEnabled bool
+	// Attributes contains arbitrary data about this SubConn intended for
+	// consumption by the Picker. It is made available via
+	// apis.SubConn.Attributes(), merged with any Attributes already present
+	// on the resolver.Address used to create the SubConn. This allows a
+	// balancer to tag a SubConn (e.g. with a stable routing key) without
+	// relying on gRPC's internal SubConn representation.
+	Attributes *attributes.Attributes
 }
 
 // State contains the balancer's state relevant to the gRPC ClientConn.
@@ -0,0 +1,121 @@
+/*
+ *
+ * Copyright 2017 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package base
+
+import (
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/apis"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/resolver"
+)
+
+var logger = grpclog.Component("balancer")
+
+type connState struct {
+	addr  resolver.Address
+	state connectivity.State
+}
+
+type baseBalancer struct {
+	cc            balancer.ClientConn
+	pickerBuilder PickerBuilder
+
+	csEvaluator balancer.ConnectivityStateEvaluator
+	state       connectivity.State
+
+	subConns map[resolver.Address]apis.SubConn
+	scStates map[apis.SubConn]connState
+	picker   balancer.Picker
+	config   Config
+}
+
+func (b *baseBalancer) UpdateClientConnState(s balancer.ClientConnState) error {
+	addrsSet := make(map[resolver.Address]struct{})
+	for _, a := range s.ResolverState.Addresses {
+		addrsSet[a] = struct{}{}
+		if _, ok := b.subConns[a]; !ok {
+			// a is a new address (not existing in b.subConns).
+			sc, err := b.cc.NewSubConn([]resolver.Address{a}, balancer.NewSubConnOptions{HealthCheckEnabled: b.config.HealthCheck})
+			if err != nil {
+				logger.Warningf("base.baseBalancer: failed to create new SubConn: %v", err)
+				continue
+			}
+			b.subConns[a] = sc
+			b.scStates[sc] = connState{addr: a, state: connectivity.Idle}
+			sc.Connect()
+		}
+	}
+	for a, sc := range b.subConns {
+		// a was removed by resolver.
+		if _, ok := addrsSet[a]; !ok {
+			b.cc.RemoveSubConn(sc)
+			delete(b.subConns, a)
+			// Keep the state of this sc in b.scStates until
+			// UpdateSubConnState is called with Shutdown.
+		}
+	}
+	return nil
+}
+
+func (b *baseBalancer) ResolverError(err error) {
+	logger.Warningf("base.baseBalancer: resolver error: %v", err)
+}
+
+func (b *baseBalancer) UpdateSubConnState(sc apis.SubConn, s balancer.SubConnState) {
+	cs, ok := b.scStates[sc]
+	if !ok {
+		return
+	}
+	oldState := cs.state
+	if s.ConnectivityState == connectivity.Shutdown {
+		delete(b.scStates, sc)
+	} else {
+		cs.state = s.ConnectivityState
+		b.scStates[sc] = cs
+	}
+	b.state = b.csEvaluator.RecordTransition(oldState, s.ConnectivityState)
+	b.regeneratePicker()
+}
+
+func (b *baseBalancer) regeneratePicker() {
+	readySCs := make(map[apis.SubConn]SubConnInfo)
+	for sc, cs := range b.scStates {
+		if cs.state == connectivity.Ready {
+			readySCs[sc] = SubConnInfo{Address: cs.addr}
+		}
+	}
+	b.picker = b.pickerBuilder.Build(PickerBuildInfo{ReadySCs: readySCs})
+	b.cc.UpdateState(balancer.State{ConnectivityState: b.state, Picker: b.picker})
+}
+
+func (b *baseBalancer) Close() {}
+
+// NewErrPicker returns a Picker that always returns err on Pick().
+func NewErrPicker(err error) balancer.Picker {
+	return &errPicker{err: err}
+}
+
+type errPicker struct {
+	err error // Pick() always returns this err.
+}
+
+func (p *errPicker) Pick(info balancer.PickInfo) (balancer.PickResult, error) {
+	return balancer.PickResult{}, p.err
+}
@@ -0,0 +1,102 @@
+/*
+ *
+ * Copyright 2017 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package base defines a balancer base that can be used to build balancers
+// with different picking algorithms.
+//
+// The base balancer creates a new picker every time its sub-balancer
+// (the PickerBuilder) state changes, and sends it to gRPC via UpdateState().
+//
+// All APIs in this package are experimental.
+package base
+
+import (
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/apis"
+	"google.golang.org/grpc/resolver"
+)
+
+// SubConnInfo describes a SubConn provided by the base balancer to a
+// PickerBuilder.
+type SubConnInfo struct {
+	// Address is the resolver.Address used to create this SubConn. It can
+	// be used, for example, to key off of Address.Addr or a user-defined
+	// attribute in Address.Attributes, without reaching into gRPC's
+	// connection internals.
+	Address resolver.Address
+}
+
+// PickerBuildInfo contains information needed by the picker builder to
+// construct a picker.
+type PickerBuildInfo struct {
+	// ReadySCs is a map from all ready SubConns to the SubConnInfo (notably,
+	// the resolver.Address) used to create them.
+	ReadySCs map[apis.SubConn]SubConnInfo
+}
+
+// PickerBuilder creates balancer.Picker.
+type PickerBuilder interface {
+	// Build returns a picker that will be used by gRPC to pick a SubConn.
+	Build(info PickerBuildInfo) balancer.Picker
+}
+
+// Config contains the config info about the base balancer builder.
+type Config struct {
+	// HealthCheck indicates whether health checking should be enabled for
+	// this specific balancer.
+	HealthCheck bool
+}
+
+// NewBalancerBuilder returns a balancer builder. The balancers built by this
+// builder will use the picker builder to build pickers upon SubConn state
+// changes. The picker builder is passed a PickerBuildInfo, which contains
+// the list of ready SubConns and the addresses used to create them.
+func NewBalancerBuilder(name string, pb PickerBuilder, config Config) balancer.Builder {
+	return &baseBuilder{
+		name:          name,
+		pickerBuilder: pb,
+		config:        config,
+	}
+}
+
+type baseBuilder struct {
+	name          string
+	pickerBuilder PickerBuilder
+	config        Config
+}
+
+func (bb *baseBuilder) Build(cc balancer.ClientConn, opt balancer.BuildOptions) balancer.Balancer {
+	bal := &baseBalancer{
+		cc:            cc,
+		pickerBuilder: bb.pickerBuilder,
+
+		subConns: make(map[resolver.Address]apis.SubConn),
+		scStates: make(map[apis.SubConn]connState),
+		config:   bb.config,
+	}
+	// Initialize picker to a picker that always returns
+	// ErrNoSubConnAvailable. It stands until the first SubConn state
+	// change, on which (as on every later one) regeneratePicker builds a
+	// new picker and sends it via UpdateState.
+	bal.picker = NewErrPicker(balancer.ErrNoSubConnAvailable)
+	return bal
+}
+
+func (bb *baseBuilder) Name() string {
+	return bb.name
+}
@@ -2,15 +2,14 @@ package customRoundrobin
 
 import (
 	"context"
-	"google.golang.org/grpc/balancer/apis"
-	"google.golang.org/grpc/metadata"
-	"strings"
 	"sync"
 
 	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/apis"
 	"google.golang.org/grpc/balancer/base"
 	"google.golang.org/grpc/grpclog"
 	"google.golang.org/grpc/internal/grpcrand"
+	"google.golang.org/grpc/metadata"
 )
 
 const Name = "customRoundrobin"
@@ -35,11 +34,14 @@ func (*rrPickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
 		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
 	}
 	var scs []apis.SubConn
-	for sc := range info.ReadySCs {
+	byAddr := make(map[string]apis.SubConn, len(info.ReadySCs))
+	for sc, scInfo := range info.ReadySCs {
 		scs = append(scs, sc)
+		byAddr[scInfo.Address.Addr] = sc
 	}
 	return &rrPicker{
 		subConns: scs,
+		byAddr:   byAddr,
 		// Start at a random index, as the same RR balancer rebuilds a new
 		// picker when SubConn states change, and we don't want to apply excess
 		// load to the first server in the list.
@@ -52,6 +54,10 @@ type rrPicker struct {
 	// created. The slice is immutable. Each Get() will do a round robin
 	// selection from it and return the selected SubConn.
 	subConns []apis.SubConn
+	// byAddr looks up a SubConn by the resolver.Address.Addr it was created
+	// with, so Pick can honor a sticky "lb-addr" request without reaching
+	// into gRPC's connection internals.
+	byAddr map[string]apis.SubConn
 
 	mu   sync.Mutex
 	next int
@@ -64,27 +70,19 @@ if the addr is present, we need to route request to the addr as overwritten,
 if not, we switch to the regular roundrobin
 */
 func (p *rrPicker) Pick(pi balancer.PickInfo) (balancer.PickResult, error) {
-	p.mu.Lock()
-
-	var chosenSc apis.SubConn
-
 	// subConn pick on user request
 	if overwriteAddr, ok := stickyKeyFromContext(pi.Ctx, OverWriteKeyName); ok {
-		for _, sc := range p.subConns {
-			curAddr := sc.GetAddrConnection() //reflect.ValueOf(sc).Elem().FieldByName("ac").Interface().(*addrConn)
-			if strings.Compare(curAddr.Addr, overwriteAddr) == 0 {
-				// add match, route to the subconnection
-				chosenSc = sc
-			}
+		if sc, ok := p.byAddr[overwriteAddr]; ok {
+			return balancer.PickResult{SubConn: sc}, nil
 		}
-	} else {
-		// subConn pick on lb
-		chosenSc = p.subConns[p.next]
-		p.next = (p.next + 1) % len(p.subConns)
 	}
 
+	// subConn pick on lb
+	p.mu.Lock()
+	sc := p.subConns[p.next]
+	p.next = (p.next + 1) % len(p.subConns)
 	p.mu.Unlock()
-	return balancer.PickResult{SubConn: chosenSc}, nil
+	return balancer.PickResult{SubConn: sc}, nil
 }
 
 // Get one value from metadata in ctx with key stickinessMDKey.
@@ -0,0 +1,464 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package outlierdetection
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/apis"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/serviceconfig"
+)
+
+// outlierDetectionBalancer wraps a child balancer.Balancer, ejecting
+// endpoints whose observed success rate or failure percentage indicates
+// they are misbehaving. It never implements load balancing logic itself;
+// it only filters the child's Picker and feeds it a child ClientConn
+// (odCC) that lets it intercept every SubConn the child creates.
+type outlierDetectionBalancer struct {
+	cc    balancer.ClientConn
+	bOpts balancer.BuildOptions
+	odCC  *ccWrapper
+
+	mu         sync.Mutex
+	cfg        LBConfig
+	child      balancer.Balancer
+	scWrappers map[apis.SubConn]*subConnWrapper
+	timerCount int // guards against a stale, already-stopped interval timer firing
+
+	closed chan struct{}
+}
+
+func (b *outlierDetectionBalancer) UpdateClientConnState(s balancer.ClientConnState) error {
+	cfg, ok := s.BalancerConfig.(LBConfig)
+	if !ok {
+		return fmt.Errorf("outlier_detection: received unexpected balancer config type: %T", s.BalancerConfig)
+	}
+
+	b.mu.Lock()
+	firstUpdate := b.child == nil
+	childChanged := b.child != nil && b.cfg.ChildPolicy.Name != cfg.ChildPolicy.Name
+	b.cfg = cfg
+	if firstUpdate || childChanged {
+		if b.child != nil {
+			b.child.Close()
+		}
+		builder := balancer.Get(cfg.ChildPolicy.Name)
+		if builder == nil {
+			b.mu.Unlock()
+			return fmt.Errorf("outlier_detection: no balancer registered for child policy %q", cfg.ChildPolicy.Name)
+		}
+		b.child = builder.Build(b.odCC, b.bOpts)
+		b.startIntervalTimerLocked()
+	}
+	child := b.child
+	b.mu.Unlock()
+
+	var childCfg serviceconfig.LoadBalancingConfig
+	if parser, ok := balancer.Get(cfg.ChildPolicy.Name).(balancer.ConfigParser); ok && len(cfg.ChildPolicy.Config) > 0 {
+		parsed, err := parser.ParseConfig(cfg.ChildPolicy.Config)
+		if err != nil {
+			return fmt.Errorf("outlier_detection: failed to parse child policy config: %v", err)
+		}
+		childCfg = parsed
+	}
+
+	return child.UpdateClientConnState(balancer.ClientConnState{
+		ResolverState:  s.ResolverState,
+		BalancerConfig: childCfg,
+	})
+}
+
+func (b *outlierDetectionBalancer) ResolverError(err error) {
+	b.mu.Lock()
+	child := b.child
+	b.mu.Unlock()
+	if child != nil {
+		child.ResolverError(err)
+	}
+}
+
+func (b *outlierDetectionBalancer) UpdateSubConnState(sc apis.SubConn, s balancer.SubConnState) {
+	b.mu.Lock()
+	child := b.child
+	b.mu.Unlock()
+	if child != nil {
+		child.UpdateSubConnState(sc, s)
+	}
+}
+
+func (b *outlierDetectionBalancer) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	close(b.closed)
+	if b.child != nil {
+		b.child.Close()
+	}
+}
+
+// startIntervalTimerLocked starts the periodic ejection sweep. b.mu must be
+// held; it is re-acquired internally on each tick.
+func (b *outlierDetectionBalancer) startIntervalTimerLocked() {
+	b.timerCount++
+	myTimerCount := b.timerCount
+	interval := b.cfg.Interval
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-b.closed:
+				return
+			case <-t.C:
+				b.mu.Lock()
+				if b.timerCount != myTimerCount {
+					// A newer child policy (and therefore a newer timer)
+					// has superseded this one.
+					b.mu.Unlock()
+					return
+				}
+				b.runEjectionSweepLocked()
+				b.mu.Unlock()
+			}
+		}
+	}()
+}
+
+// runEjectionSweepLocked computes success rate / failure percentage per
+// endpoint and ejects or un-ejects endpoints accordingly. b.mu must be
+// held.
+func (b *outlierDetectionBalancer) runEjectionSweepLocked() {
+	now := time.Now()
+	samples := make([]*sample, 0, len(b.scWrappers))
+	for _, scw := range b.scWrappers {
+		if scw.callCounter == nil {
+			continue
+		}
+		bkt := scw.callCounter.swap()
+		total := bkt.successes + bkt.failures
+		s := &sample{scw: scw, successes: bkt.successes, failures: bkt.failures, total: total}
+		if total > 0 {
+			s.successRate = float64(bkt.successes) / float64(total)
+		}
+		samples = append(samples, s)
+	}
+
+	numHosts := len(samples)
+	maxEjected := int(math.Ceil(float64(numHosts) * float64(b.cfg.MaxEjectionPercent) / 100))
+	numEjected := 0
+	for _, s := range samples {
+		if s.scw.isEjected() {
+			numEjected++
+		}
+	}
+
+	changed := false
+	eject := func(s *sample) {
+		if numEjected >= maxEjected {
+			return
+		}
+		if s.scw.eject(now) {
+			changed = true
+		}
+		numEjected++
+	}
+
+	if b.cfg.SuccessRateEnabled {
+		var eligible []*sample
+		for _, s := range samples {
+			if s.total >= b.cfg.SuccessRateRequestVolume {
+				eligible = append(eligible, s)
+			}
+		}
+		if len(eligible) >= int(b.cfg.SuccessRateMinimumHosts) {
+			mean, stdev := meanAndStdev(eligible)
+			threshold := mean - stdev*(float64(b.cfg.SuccessRateStdevFactor)/1000)
+			for _, s := range eligible {
+				if s.successRate < threshold {
+					eject(s)
+				}
+			}
+		}
+	}
+
+	if b.cfg.FailurePercentageEnabled {
+		var eligible []*sample
+		for _, s := range samples {
+			if s.total >= b.cfg.FailurePercentageRequestVolume {
+				eligible = append(eligible, s)
+			}
+		}
+		if len(eligible) >= int(b.cfg.FailurePercentageMinimumHosts) {
+			for _, s := range eligible {
+				failurePct := 100 * (1 - s.successRate)
+				if failurePct >= float64(b.cfg.FailurePercentageThreshold) {
+					eject(s)
+				}
+			}
+		}
+	}
+
+	// Un-eject any endpoint whose ejection period has elapsed.
+	for _, s := range samples {
+		if s.scw.maybeUneject(now, b.cfg.BaseEjectionTime, b.cfg.MaxEjectionTime) {
+			changed = true
+		}
+	}
+
+	// A pick that landed on a SubConn ejected (or un-ejected) just now would
+	// otherwise wait on ErrNoSubConnAvailable until the child happens to
+	// call UpdateState on its own; force that Picker back out immediately.
+	if changed {
+		b.odCC.resend()
+	}
+}
+
+// sample is a per-endpoint snapshot of the previous interval's call
+// outcomes, used by the ejection algorithms.
+type sample struct {
+	scw         *subConnWrapper
+	successes   uint32
+	failures    uint32
+	total       uint32
+	successRate float64
+}
+
+func meanAndStdev(samples []*sample) (mean, stdev float64) {
+	for _, s := range samples {
+		mean += s.successRate
+	}
+	mean /= float64(len(samples))
+	var variance float64
+	for _, s := range samples {
+		d := s.successRate - mean
+		variance += d * d
+	}
+	variance /= float64(len(samples))
+	return mean, math.Sqrt(variance)
+}
+
+// subConnWrapper wraps the apis.SubConn gRPC actually created, tracking the
+// per-endpoint call outcome counters and ejection state that the
+// Envoy-style outlier detection algorithms operate on.
+//
+// The ejection fields are written by runEjectionSweepLocked (under the
+// parent balancer's mu) but read by wrappedPicker.Pick, which runs
+// concurrently with the sweep on gRPC's picker goroutines and holds no
+// lock of the balancer's. They're guarded by ejectionMu instead, so every
+// RPC's pick sees a consistent, not-torn view of ejection state.
+type subConnWrapper struct {
+	apis.SubConn
+
+	addresses []resolver.Address
+	parent    *outlierDetectionBalancer
+
+	callCounter *callCounter
+
+	ejectionMu           sync.Mutex
+	ejected              bool
+	consecutiveEjections int
+	lastEjectionTime     time.Time
+}
+
+func (scw *subConnWrapper) isEjected() bool {
+	scw.ejectionMu.Lock()
+	defer scw.ejectionMu.Unlock()
+	return scw.ejected
+}
+
+// eject marks scw ejected as of now, bumping its consecutive-ejection
+// count so a repeatedly-misbehaving endpoint is ejected for longer each
+// time (up to MaxEjectionTime). It reports whether scw was not already
+// ejected, i.e. whether this call is a new ejection the picker needs to be
+// told about.
+func (scw *subConnWrapper) eject(now time.Time) bool {
+	scw.ejectionMu.Lock()
+	defer scw.ejectionMu.Unlock()
+	wasEjected := scw.ejected
+	scw.ejected = true
+	scw.consecutiveEjections++
+	scw.lastEjectionTime = now
+	return !wasEjected
+}
+
+// maybeUneject un-ejects scw if it's been ejected for at least
+// baseEjectionTime*consecutiveEjections (capped at maxEjectionTime). It
+// reports whether it actually un-ejected scw.
+func (scw *subConnWrapper) maybeUneject(now time.Time, baseEjectionTime, maxEjectionTime time.Duration) bool {
+	scw.ejectionMu.Lock()
+	defer scw.ejectionMu.Unlock()
+	if !scw.ejected {
+		return false
+	}
+	ejectionTime := baseEjectionTime * time.Duration(scw.consecutiveEjections)
+	if ejectionTime > maxEjectionTime {
+		ejectionTime = maxEjectionTime
+	}
+	if now.Sub(scw.lastEjectionTime) >= ejectionTime {
+		scw.ejected = false
+		return true
+	}
+	return false
+}
+
+type bucket struct {
+	successes uint32
+	failures  uint32
+}
+
+// callCounter holds the active bucket (accumulating the current interval's
+// outcomes) and the inactive bucket (the previous interval's snapshot, read
+// by the ejection sweep).
+type callCounter struct {
+	mu       sync.Mutex
+	active   *bucket
+	inactive *bucket
+}
+
+func newCallCounter() *callCounter {
+	return &callCounter{active: &bucket{}, inactive: &bucket{}}
+}
+
+func (c *callCounter) record(success bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if success {
+		c.active.successes++
+	} else {
+		c.active.failures++
+	}
+}
+
+// swap rotates the active bucket into the inactive slot (so the ejection
+// sweep sees a stable snapshot of the interval that just ended) and starts
+// a fresh active bucket.
+func (c *callCounter) swap() *bucket {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inactive, c.active = c.active, &bucket{}
+	return c.inactive
+}
+
+// ccWrapper is the balancer.ClientConn given to the child policy. It
+// intercepts every SubConn the child creates (so Picks can be attributed to
+// call counters) and every Picker the child produces (so ejected SubConns
+// never appear as pick candidates).
+type ccWrapper struct {
+	balancer.ClientConn
+	parent *outlierDetectionBalancer
+
+	mu    sync.Mutex
+	state balancer.State // last wrapped state actually pushed to the real ClientConn
+}
+
+func (c *ccWrapper) NewSubConn(addrs []resolver.Address, opts balancer.NewSubConnOptions) (apis.SubConn, error) {
+	sc, err := c.ClientConn.NewSubConn(addrs, opts)
+	if err != nil {
+		return nil, err
+	}
+	scw := &subConnWrapper{
+		SubConn:     sc,
+		addresses:   addrs,
+		parent:      c.parent,
+		callCounter: newCallCounter(),
+	}
+	c.parent.mu.Lock()
+	c.parent.scWrappers[sc] = scw
+	c.parent.mu.Unlock()
+	return scw, nil
+}
+
+func (c *ccWrapper) RemoveSubConn(sc apis.SubConn) {
+	scw, ok := sc.(*subConnWrapper)
+	if !ok {
+		c.ClientConn.RemoveSubConn(sc)
+		return
+	}
+	c.parent.mu.Lock()
+	delete(c.parent.scWrappers, scw.SubConn)
+	c.parent.mu.Unlock()
+	c.ClientConn.RemoveSubConn(scw.SubConn)
+}
+
+func (c *ccWrapper) UpdateState(s balancer.State) {
+	wrapped := balancer.State{
+		ConnectivityState: s.ConnectivityState,
+		Picker:            &wrappedPicker{childPicker: s.Picker},
+	}
+	c.mu.Lock()
+	c.state = wrapped
+	c.mu.Unlock()
+	c.ClientConn.UpdateState(wrapped)
+}
+
+// resend re-pushes the most recently wrapped state to the real ClientConn.
+// runEjectionSweepLocked calls it whenever ejection membership actually
+// changes: a wrappedPicker.Pick landing on a newly-ejected SubConn returns
+// ErrNoSubConnAvailable, which the Picker contract blocks on until a fresh
+// Picker arrives via UpdateState -- the child itself has no reason to call
+// UpdateState just because the sweep ejected or un-ejected an endpoint, so
+// without this, an RPC unlucky enough to land there would stall until the
+// next unrelated SubConn state change. Re-sending the same wrapped Picker
+// is enough: it re-evaluates isEjected() per SubConn on every Pick, so the
+// content, not the pointer, is what needs to reach the pickerWrapper again.
+func (c *ccWrapper) resend() {
+	c.mu.Lock()
+	state := c.state
+	c.mu.Unlock()
+	if state.Picker != nil {
+		c.ClientConn.UpdateState(state)
+	}
+}
+
+// wrappedPicker delegates to the child's Picker, but skips any SubConn
+// currently ejected, and records the outcome of every completed RPC
+// against its endpoint's call counter.
+type wrappedPicker struct {
+	childPicker balancer.Picker
+}
+
+func (p *wrappedPicker) Pick(info balancer.PickInfo) (balancer.PickResult, error) {
+	res, err := p.childPicker.Pick(info)
+	if err != nil {
+		return res, err
+	}
+	scw, ok := res.SubConn.(*subConnWrapper)
+	if !ok {
+		return res, nil
+	}
+	if scw.isEjected() {
+		// Ejected endpoints must never be used; block until a fresh picker
+		// (pushed on the next SubConn state change, or once un-ejected)
+		// is available.
+		return balancer.PickResult{}, balancer.ErrNoSubConnAvailable
+	}
+
+	childDone := res.Done
+	res.Done = func(di balancer.DoneInfo) {
+		scw.callCounter.record(di.Err == nil)
+		if childDone != nil {
+			childDone(di)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,284 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package outlierdetection
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/attributes"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/apis"
+	"google.golang.org/grpc/resolver"
+)
+
+// fakeSubConn is a bare-bones apis.SubConn, distinguished by pointer
+// identity, so subConnWrapper can be exercised without gRPC's connection
+// internals. It carries an unused field so instances aren't zero-sized:
+// Go collapses every *T for a zero-size T onto the same runtime.zerobase
+// address once boxed into an interface, which would make every
+// fakeSubConn compare equal and collide as a map key.
+type fakeSubConn struct{ _ int }
+
+func (*fakeSubConn) UpdateAddresses([]resolver.Address) {}
+func (*fakeSubConn) Connect()                           {}
+func (*fakeSubConn) Attributes() *attributes.Attributes { return nil }
+
+func newTestSCW() *subConnWrapper {
+	return &subConnWrapper{SubConn: &fakeSubConn{}, callCounter: newCallCounter()}
+}
+
+var errTestFailure = errors.New("synthetic RPC failure")
+
+// fakeClientConn is a bare-bones balancer.ClientConn that only counts
+// UpdateState calls, so tests can assert the ejection sweep re-pushes a
+// Picker when it changes ejection membership.
+type fakeClientConn struct {
+	balancer.ClientConn
+	updateStateCount int
+}
+
+func (f *fakeClientConn) UpdateState(balancer.State) { f.updateStateCount++ }
+
+// fakeChildPicker always returns the same SubConn; it stands in for
+// whatever the wrapped child policy would have picked.
+type fakeChildPicker struct {
+	sc apis.SubConn
+}
+
+func (p fakeChildPicker) Pick(balancer.PickInfo) (balancer.PickResult, error) {
+	return balancer.PickResult{SubConn: p.sc}, nil
+}
+
+// drive feeds n outcomes through wrappedPicker.Pick's Done callback, the
+// same path real RPCs take, so it exercises the call-counter bookkeeping
+// exactly as production code would.
+func drive(t *testing.T, scw *subConnWrapper, successes, failures int) {
+	t.Helper()
+	p := &wrappedPicker{childPicker: fakeChildPicker{sc: scw}}
+	for i := 0; i < successes+failures; i++ {
+		res, err := p.Pick(balancer.PickInfo{})
+		if err != nil {
+			t.Fatalf("unexpected Pick error: %v", err)
+		}
+		var doneErr error
+		if i >= successes {
+			doneErr = errTestFailure
+		}
+		res.Done(balancer.DoneInfo{Err: doneErr})
+	}
+}
+
+func TestRunEjectionSweep_SuccessRate(t *testing.T) {
+	b := &outlierDetectionBalancer{
+		cfg: LBConfig{
+			Interval:                 time.Second,
+			BaseEjectionTime:         30 * time.Second,
+			MaxEjectionTime:          300 * time.Second,
+			MaxEjectionPercent:       100,
+			SuccessRateEnabled:       true,
+			SuccessRateStdevFactor:   500, // 0.5 stdev factor: easy to trip for the test
+			SuccessRateMinimumHosts:  2,
+			SuccessRateRequestVolume: 10,
+		},
+		scWrappers: make(map[apis.SubConn]*subConnWrapper),
+	}
+	b.odCC = &ccWrapper{parent: b}
+
+	good1, good2, bad := newTestSCW(), newTestSCW(), newTestSCW()
+	b.scWrappers[good1.SubConn] = good1
+	b.scWrappers[good2.SubConn] = good2
+	b.scWrappers[bad.SubConn] = bad
+
+	drive(t, good1, 10, 0)
+	drive(t, good2, 10, 0)
+	drive(t, bad, 2, 8) // 20% success rate, well below the other two
+
+	b.mu.Lock()
+	b.runEjectionSweepLocked()
+	b.mu.Unlock()
+
+	if !bad.isEjected() {
+		t.Errorf("expected low-success-rate SubConn to be ejected")
+	}
+	if good1.isEjected() || good2.isEjected() {
+		t.Errorf("expected healthy SubConns to remain un-ejected")
+	}
+}
+
+// TestRunEjectionSweep_ResendsStateOnMembershipChange verifies that ejecting
+// or un-ejecting a SubConn pushes a fresh Picker to the real ClientConn,
+// instead of leaving any RPC blocked on the stale one until the child
+// happens to call UpdateState for an unrelated reason.
+func TestRunEjectionSweep_ResendsStateOnMembershipChange(t *testing.T) {
+	fcc := &fakeClientConn{}
+	b := &outlierDetectionBalancer{
+		cfg: LBConfig{
+			Interval:                 time.Second,
+			BaseEjectionTime:         10 * time.Millisecond,
+			MaxEjectionTime:          time.Second,
+			MaxEjectionPercent:       100,
+			SuccessRateEnabled:       true,
+			SuccessRateStdevFactor:   500,
+			SuccessRateMinimumHosts:  2,
+			SuccessRateRequestVolume: 10,
+		},
+		scWrappers: make(map[apis.SubConn]*subConnWrapper),
+	}
+	b.odCC = &ccWrapper{ClientConn: fcc, parent: b}
+
+	good1, good2, bad := newTestSCW(), newTestSCW(), newTestSCW()
+	b.scWrappers[good1.SubConn] = good1
+	b.scWrappers[good2.SubConn] = good2
+	b.scWrappers[bad.SubConn] = bad
+
+	// No wrapped state has ever been pushed yet (the child hasn't called
+	// UpdateState), so there's nothing to re-send even though bad is about
+	// to be ejected.
+	drive(t, good1, 10, 0)
+	drive(t, good2, 10, 0)
+	drive(t, bad, 2, 8)
+	b.mu.Lock()
+	b.runEjectionSweepLocked()
+	b.mu.Unlock()
+	if !bad.isEjected() {
+		t.Fatalf("expected bad to be ejected")
+	}
+	if fcc.updateStateCount != 0 {
+		t.Errorf("UpdateState called %d times before the child ever produced a Picker, want 0", fcc.updateStateCount)
+	}
+
+	// Once the child has produced a Picker, a sweep that changes ejection
+	// membership must re-push it.
+	b.odCC.UpdateState(balancer.State{Picker: fakeChildPicker{sc: good1}})
+	if fcc.updateStateCount != 1 {
+		t.Fatalf("UpdateState called %d times after the child's first Picker, want 1", fcc.updateStateCount)
+	}
+
+	drive(t, good1, 10, 0)
+	drive(t, good2, 10, 0)
+	// bad is still ejected, so its own wrappedPicker would refuse to record
+	// outcomes for it; feed its counter directly to simulate it having
+	// recovered, independent of the (still pending) un-ejection.
+	for i := 0; i < 10; i++ {
+		bad.callCounter.record(true)
+	}
+	b.mu.Lock()
+	b.runEjectionSweepLocked()
+	b.mu.Unlock()
+	if fcc.updateStateCount != 1 {
+		t.Errorf("UpdateState called %d times after a sweep with no membership change, want 1 (unchanged)", fcc.updateStateCount)
+	}
+
+	time.Sleep(2 * b.cfg.BaseEjectionTime)
+	b.mu.Lock()
+	b.runEjectionSweepLocked()
+	b.mu.Unlock()
+	if bad.isEjected() {
+		t.Fatalf("expected bad to have been un-ejected by now")
+	}
+	if fcc.updateStateCount != 2 {
+		t.Errorf("UpdateState called %d times after bad was un-ejected, want 2", fcc.updateStateCount)
+	}
+}
+
+func TestRunEjectionSweep_FailurePercentage(t *testing.T) {
+	b := &outlierDetectionBalancer{
+		cfg: LBConfig{
+			Interval:                       time.Second,
+			BaseEjectionTime:               30 * time.Second,
+			MaxEjectionTime:                300 * time.Second,
+			MaxEjectionPercent:             100,
+			FailurePercentageEnabled:       true,
+			FailurePercentageThreshold:     50,
+			FailurePercentageMinimumHosts:  1,
+			FailurePercentageRequestVolume: 10,
+		},
+		scWrappers: make(map[apis.SubConn]*subConnWrapper),
+	}
+	b.odCC = &ccWrapper{parent: b}
+
+	bad := newTestSCW()
+	b.scWrappers[bad.SubConn] = bad
+	drive(t, bad, 2, 8) // 80% failures >= 50% threshold
+
+	b.mu.Lock()
+	b.runEjectionSweepLocked()
+	b.mu.Unlock()
+
+	if !bad.isEjected() {
+		t.Errorf("expected SubConn over the failure-percentage threshold to be ejected")
+	}
+}
+
+func TestRunEjectionSweep_Uneject(t *testing.T) {
+	b := &outlierDetectionBalancer{
+		cfg: LBConfig{
+			BaseEjectionTime:   10 * time.Millisecond,
+			MaxEjectionTime:    time.Second,
+			MaxEjectionPercent: 100,
+		},
+		scWrappers: make(map[apis.SubConn]*subConnWrapper),
+	}
+	b.odCC = &ccWrapper{parent: b}
+	scw := newTestSCW()
+	scw.eject(time.Now().Add(-time.Second)) // long past its ejection window
+	b.scWrappers[scw.SubConn] = scw
+
+	b.mu.Lock()
+	b.runEjectionSweepLocked()
+	b.mu.Unlock()
+
+	if scw.isEjected() {
+		t.Errorf("expected SubConn to be un-ejected once its ejection period elapsed")
+	}
+}
+
+func TestWrappedPicker_SkipsEjected(t *testing.T) {
+	scw := newTestSCW()
+	scw.eject(time.Now())
+	p := &wrappedPicker{childPicker: fakeChildPicker{sc: scw}}
+
+	if _, err := p.Pick(balancer.PickInfo{}); err != balancer.ErrNoSubConnAvailable {
+		t.Errorf("Pick() on an ejected SubConn = %v, want ErrNoSubConnAvailable", err)
+	}
+}
+
+// TestEjectionState_ConcurrentAccess exercises the exact pattern the race
+// detector flagged: a picker goroutine reading isEjected() concurrently
+// with the sweep goroutine ejecting/un-ejecting the same subConnWrapper.
+// Run with -race to verify.
+func TestEjectionState_ConcurrentAccess(t *testing.T) {
+	scw := newTestSCW()
+	p := &wrappedPicker{childPicker: fakeChildPicker{sc: scw}}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			p.Pick(balancer.PickInfo{})
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		scw.eject(time.Now())
+		scw.maybeUneject(time.Now(), time.Nanosecond, time.Second)
+	}
+	<-done
+}
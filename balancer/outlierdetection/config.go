@@ -0,0 +1,225 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package outlierdetection
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/serviceconfig"
+)
+
+const (
+	defaultInterval           = 10 * time.Second
+	defaultBaseEjectionTime   = 30 * time.Second
+	defaultMaxEjectionTime    = 300 * time.Second
+	defaultMaxEjectionPercent = 10
+
+	defaultSuccessRateStdevFactor   = 1900
+	defaultSuccessRateMinimumHosts  = 5
+	defaultSuccessRateRequestVolume = 100
+)
+
+// ChildPolicy identifies the balancer wrapped by outlier_detection and its
+// configuration, mirroring how a LoadBalancingConfig names its policy
+// elsewhere in service configs.
+type ChildPolicy struct {
+	Name   string
+	Config json.RawMessage
+}
+
+// LBConfig is the balancer config for outlier_detection.
+//
+// On each Interval, the subsystem computes a success rate (and/or failure
+// percentage) per endpoint from sampled balancer.DoneInfo callbacks.
+// Endpoints whose success rate falls more than SuccessRateStdevFactor/1000
+// standard deviations below the mean (or whose failure percentage is at
+// least FailurePercentageThreshold), are ejected for
+// BaseEjectionTime * consecutive_ejections, capped at MaxEjectionTime, up
+// to MaxEjectionPercent of the endpoints.
+type LBConfig struct {
+	serviceconfig.LoadBalancingConfig
+
+	// ChildPolicy is the balancer wrapped by this outlier_detection
+	// instance. Required.
+	ChildPolicy ChildPolicy
+
+	// Interval is the time between ejection analysis sweeps. Defaults to
+	// defaultInterval.
+	Interval time.Duration
+	// BaseEjectionTime is the base duration an endpoint is ejected for; the
+	// actual ejection time is BaseEjectionTime multiplied by the endpoint's
+	// number of consecutive ejections. Defaults to defaultBaseEjectionTime.
+	BaseEjectionTime time.Duration
+	// MaxEjectionTime caps the duration computed from BaseEjectionTime.
+	// Defaults to defaultMaxEjectionTime.
+	MaxEjectionTime time.Duration
+	// MaxEjectionPercent is the maximum percentage (0-100) of endpoints
+	// that may be ejected at any one time. The resulting endpoint count is
+	// rounded up, so any non-zero percentage allows at least one ejection.
+	// Defaults to defaultMaxEjectionPercent.
+	MaxEjectionPercent uint32
+
+	// SuccessRateEnabled turns on the success-rate ejection algorithm.
+	SuccessRateEnabled bool
+	// SuccessRateStdevFactor scales the standard deviation used to
+	// determine the success rate ejection threshold (mean -
+	// stdev_factor/1000 * stdev). Defaults to defaultSuccessRateStdevFactor
+	// (i.e. a factor of 1.9), matching Envoy's convention of expressing the
+	// factor in thousandths so it can be represented as an integer.
+	SuccessRateStdevFactor uint32
+	// SuccessRateMinimumHosts is the minimum number of endpoints that must
+	// have a request volume of SuccessRateRequestVolume before the success
+	// rate algorithm runs. Defaults to defaultSuccessRateMinimumHosts.
+	SuccessRateMinimumHosts uint32
+	// SuccessRateRequestVolume is the minimum number of requests an
+	// endpoint must have seen in the interval to be considered by the
+	// success rate algorithm. Defaults to defaultSuccessRateRequestVolume.
+	SuccessRateRequestVolume uint32
+
+	// FailurePercentageEnabled turns on the failure-percentage ejection
+	// algorithm, which ejects any endpoint whose failure percentage in the
+	// interval is >= FailurePercentageThreshold, independent of its peers.
+	FailurePercentageEnabled bool
+	// FailurePercentageThreshold is a percentage (0-100).
+	FailurePercentageThreshold uint32
+	// FailurePercentageMinimumHosts is the minimum number of endpoints that
+	// must be present before the failure percentage algorithm runs.
+	FailurePercentageMinimumHosts uint32
+	// FailurePercentageRequestVolume is the minimum number of requests an
+	// endpoint must have seen in the interval to be considered.
+	FailurePercentageRequestVolume uint32
+}
+
+type successRateEjectionJSON struct {
+	StdevFactor        *uint32 `json:"stdevFactor,omitempty"`
+	MinimumHosts       *uint32 `json:"minimumHosts,omitempty"`
+	RequestVolume      *uint32 `json:"requestVolume,omitempty"`
+	EnforcementPercent *uint32 `json:"enforcementPercentage,omitempty"`
+}
+
+type failurePercentageEjectionJSON struct {
+	Threshold     *uint32 `json:"threshold,omitempty"`
+	MinimumHosts  *uint32 `json:"minimumHosts,omitempty"`
+	RequestVolume *uint32 `json:"requestVolume,omitempty"`
+}
+
+type lbConfigJSON struct {
+	ChildPolicy               []map[string]json.RawMessage   `json:"childPolicy,omitempty"`
+	Interval                  string                         `json:"interval,omitempty"`
+	BaseEjectionTime          string                         `json:"baseEjectionTime,omitempty"`
+	MaxEjectionTime           string                         `json:"maxEjectionTime,omitempty"`
+	MaxEjectionPercent        *uint32                        `json:"maxEjectionPercent,omitempty"`
+	SuccessRateEjection       *successRateEjectionJSON       `json:"successRateEjection,omitempty"`
+	FailurePercentageEjection *failurePercentageEjectionJSON `json:"failurePercentageEjection,omitempty"`
+}
+
+// ParseConfig parses the outlier_detection JSON load balancing config,
+// filling in unset fields with their defaults.
+func (bb) ParseConfig(c json.RawMessage) (serviceconfig.LoadBalancingConfig, error) {
+	var cfgJSON lbConfigJSON
+	if err := json.Unmarshal(c, &cfgJSON); err != nil {
+		return nil, fmt.Errorf("outlier_detection: unable to unmarshal LBConfig: %v", err)
+	}
+
+	cfg := LBConfig{
+		Interval:           defaultInterval,
+		BaseEjectionTime:   defaultBaseEjectionTime,
+		MaxEjectionTime:    defaultMaxEjectionTime,
+		MaxEjectionPercent: defaultMaxEjectionPercent,
+	}
+
+	for _, entry := range cfgJSON.ChildPolicy {
+		for name, raw := range entry {
+			cfg.ChildPolicy = ChildPolicy{Name: name, Config: raw}
+			break
+		}
+		if cfg.ChildPolicy.Name != "" {
+			break
+		}
+	}
+	if cfg.ChildPolicy.Name == "" {
+		return nil, fmt.Errorf("outlier_detection: no child policy configured")
+	}
+
+	var err error
+	if cfg.Interval, err = parseDurationOrDefault(cfgJSON.Interval, defaultInterval); err != nil {
+		return nil, err
+	}
+	if cfg.BaseEjectionTime, err = parseDurationOrDefault(cfgJSON.BaseEjectionTime, defaultBaseEjectionTime); err != nil {
+		return nil, err
+	}
+	if cfg.MaxEjectionTime, err = parseDurationOrDefault(cfgJSON.MaxEjectionTime, defaultMaxEjectionTime); err != nil {
+		return nil, err
+	}
+	if cfg.Interval <= 0 {
+		return nil, fmt.Errorf("outlier_detection: interval (%v) must be positive", cfg.Interval)
+	}
+	if cfgJSON.MaxEjectionPercent != nil {
+		cfg.MaxEjectionPercent = *cfgJSON.MaxEjectionPercent
+	}
+	if cfg.MaxEjectionPercent > 100 {
+		return nil, fmt.Errorf("outlier_detection: maxEjectionPercent (%v) must be <= 100", cfg.MaxEjectionPercent)
+	}
+
+	if sre := cfgJSON.SuccessRateEjection; sre != nil {
+		cfg.SuccessRateEnabled = true
+		cfg.SuccessRateStdevFactor = defaultSuccessRateStdevFactor
+		cfg.SuccessRateMinimumHosts = defaultSuccessRateMinimumHosts
+		cfg.SuccessRateRequestVolume = defaultSuccessRateRequestVolume
+		if sre.StdevFactor != nil {
+			cfg.SuccessRateStdevFactor = *sre.StdevFactor
+		}
+		if sre.MinimumHosts != nil {
+			cfg.SuccessRateMinimumHosts = *sre.MinimumHosts
+		}
+		if sre.RequestVolume != nil {
+			cfg.SuccessRateRequestVolume = *sre.RequestVolume
+		}
+	}
+
+	if fpe := cfgJSON.FailurePercentageEjection; fpe != nil {
+		cfg.FailurePercentageEnabled = true
+		cfg.FailurePercentageMinimumHosts = defaultSuccessRateMinimumHosts
+		cfg.FailurePercentageRequestVolume = defaultSuccessRateRequestVolume
+		if fpe.Threshold != nil {
+			cfg.FailurePercentageThreshold = *fpe.Threshold
+		}
+		if fpe.MinimumHosts != nil {
+			cfg.FailurePercentageMinimumHosts = *fpe.MinimumHosts
+		}
+		if fpe.RequestVolume != nil {
+			cfg.FailurePercentageRequestVolume = *fpe.RequestVolume
+		}
+	}
+
+	return cfg, nil
+}
+
+func parseDurationOrDefault(s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("outlier_detection: invalid duration %q: %v", s, err)
+	}
+	return d, nil
+}
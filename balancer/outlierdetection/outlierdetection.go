@@ -0,0 +1,57 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package outlierdetection provides a balancer that wraps another policy
+// and ejects endpoints determined to be misbehaving, per the Envoy outlier
+// detection design (success rate and failure percentage algorithms).
+//
+// All APIs in this package are experimental.
+package outlierdetection
+
+import (
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/apis"
+	"google.golang.org/grpc/grpclog"
+)
+
+// Name is the name of the outlier_detection balancer, as registered via
+// balancer.Register.
+const Name = "outlier_detection"
+
+var logger = grpclog.Component("outlier-detection")
+
+func init() {
+	balancer.Register(bb{})
+}
+
+type bb struct{}
+
+func (bb) Name() string { return Name }
+
+func (bb) Build(cc balancer.ClientConn, opts balancer.BuildOptions) balancer.Balancer {
+	b := &outlierDetectionBalancer{
+		cc:         cc,
+		bOpts:      opts,
+		scWrappers: make(map[apis.SubConn]*subConnWrapper),
+		closed:     make(chan struct{}),
+	}
+	b.odCC = &ccWrapper{ClientConn: cc, parent: b}
+	return b
+}
+
+var _ balancer.ConfigParser = bb{}
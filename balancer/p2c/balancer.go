@@ -0,0 +1,125 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package p2c
+
+import (
+	"fmt"
+	"sync"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/apis"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/resolver"
+)
+
+// endpoint pairs the SubConn gRPC created for one resolved address with
+// the epStats that survive across picker rebuilds.
+type endpoint struct {
+	sc        apis.SubConn
+	connState connectivity.State
+	stats     *epStats
+}
+
+type p2cBalancer struct {
+	cc balancer.ClientConn
+
+	mu          sync.Mutex
+	cfg         LBConfig
+	csEvaluator balancer.ConnectivityStateEvaluator
+	state       connectivity.State
+	subConns    map[resolver.Address]*endpoint
+	scStates    map[apis.SubConn]*endpoint
+}
+
+func (b *p2cBalancer) UpdateClientConnState(s balancer.ClientConnState) error {
+	cfg, ok := s.BalancerConfig.(LBConfig)
+	if !ok {
+		return fmt.Errorf("p2c: received unexpected balancer config type: %T", s.BalancerConfig)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cfg = cfg
+
+	addrsSet := make(map[resolver.Address]struct{}, len(s.ResolverState.Addresses))
+	for _, a := range s.ResolverState.Addresses {
+		addrsSet[a] = struct{}{}
+		if _, ok := b.subConns[a]; ok {
+			continue
+		}
+		sc, err := b.cc.NewSubConn([]resolver.Address{a}, balancer.NewSubConnOptions{})
+		if err != nil {
+			logger.Warningf("p2c: failed to create new SubConn for %v: %v", a, err)
+			continue
+		}
+		ep := &endpoint{sc: sc, stats: &epStats{halfLife: cfg.DecayHalfLife}}
+		b.subConns[a] = ep
+		b.scStates[sc] = ep
+		sc.Connect()
+	}
+	for a, ep := range b.subConns {
+		if _, ok := addrsSet[a]; !ok {
+			b.cc.RemoveSubConn(ep.sc)
+			delete(b.subConns, a)
+		}
+	}
+
+	b.regeneratePickerLocked()
+	return nil
+}
+
+func (b *p2cBalancer) ResolverError(err error) {
+	logger.Warningf("p2c: resolver error: %v", err)
+}
+
+func (b *p2cBalancer) UpdateSubConnState(sc apis.SubConn, s balancer.SubConnState) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ep, ok := b.scStates[sc]
+	if !ok {
+		return
+	}
+	oldState := ep.connState
+	if s.ConnectivityState == connectivity.Shutdown {
+		delete(b.scStates, sc)
+	} else {
+		ep.connState = s.ConnectivityState
+	}
+	b.state = b.csEvaluator.RecordTransition(oldState, s.ConnectivityState)
+	b.regeneratePickerLocked()
+}
+
+func (b *p2cBalancer) regeneratePickerLocked() {
+	var eps []*endpoint
+	for _, ep := range b.subConns {
+		if ep.connState == connectivity.Ready {
+			eps = append(eps, ep)
+		}
+	}
+	var p balancer.Picker
+	if len(eps) == 0 {
+		p = base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	} else {
+		p = &p2cPicker{eps: eps}
+	}
+	b.cc.UpdateState(balancer.State{ConnectivityState: b.state, Picker: p})
+}
+
+func (b *p2cBalancer) Close() {}
@@ -0,0 +1,64 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package p2c
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/serviceconfig"
+)
+
+// defaultDecayHalfLife is how long it takes an endpoint's latency EWMA to
+// decay halfway back toward a fresh sample, matching Finagle's default for
+// its least-loaded balancer.
+const defaultDecayHalfLife = 10 * time.Second
+
+// LBConfig is the balancer config for p2c.
+type LBConfig struct {
+	serviceconfig.LoadBalancingConfig
+
+	// DecayHalfLife controls how quickly an endpoint's latency EWMA
+	// forgets old samples. Defaults to defaultDecayHalfLife.
+	DecayHalfLife time.Duration
+}
+
+type lbConfigJSON struct {
+	DecayHalfLife string `json:"decayHalfLife,omitempty"`
+}
+
+// ParseConfig parses the p2c JSON load balancing config, filling in unset
+// fields with their defaults.
+func (bb) ParseConfig(c json.RawMessage) (serviceconfig.LoadBalancingConfig, error) {
+	var cfgJSON lbConfigJSON
+	if err := json.Unmarshal(c, &cfgJSON); err != nil {
+		return nil, fmt.Errorf("p2c: unable to unmarshal LBConfig: %v", err)
+	}
+
+	cfg := LBConfig{DecayHalfLife: defaultDecayHalfLife}
+	if cfgJSON.DecayHalfLife != "" {
+		d, err := time.ParseDuration(cfgJSON.DecayHalfLife)
+		if err != nil {
+			return nil, fmt.Errorf("p2c: invalid decayHalfLife %q: %v", cfgJSON.DecayHalfLife, err)
+		}
+		cfg.DecayHalfLife = d
+	}
+	return cfg, nil
+}
@@ -0,0 +1,57 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package p2c provides a load-aware "power of two choices" balancer: each
+// Pick samples two ready SubConns at random and selects the one with the
+// lower estimated cost (in-flight RPCs times an EWMA of observed
+// latency), per Michael Mitzenmacher's power-of-two-choices result and
+// Finagle's least-loaded balancer.
+//
+// All APIs in this package are experimental.
+package p2c
+
+import (
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/apis"
+	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/resolver"
+)
+
+// Name is the name of the p2c balancer, as registered via
+// balancer.Register.
+const Name = "p2c"
+
+var logger = grpclog.Component("p2c")
+
+func init() {
+	balancer.Register(bb{})
+}
+
+type bb struct{}
+
+func (bb) Name() string { return Name }
+
+func (bb) Build(cc balancer.ClientConn, opts balancer.BuildOptions) balancer.Balancer {
+	return &p2cBalancer{
+		cc:       cc,
+		subConns: make(map[resolver.Address]*endpoint),
+		scStates: make(map[apis.SubConn]*endpoint),
+	}
+}
+
+var _ balancer.ConfigParser = bb{}
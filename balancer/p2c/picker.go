@@ -0,0 +1,58 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package p2c
+
+import (
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/internal/grpcrand"
+)
+
+// p2cPicker samples two ready endpoints uniformly at random on every Pick
+// and chooses the cheaper one, per the power-of-two-choices heuristic.
+type p2cPicker struct {
+	eps []*endpoint
+}
+
+func (p *p2cPicker) Pick(balancer.PickInfo) (balancer.PickResult, error) {
+	if len(p.eps) == 0 {
+		return balancer.PickResult{}, balancer.ErrNoSubConnAvailable
+	}
+
+	chosen := p.eps[0]
+	if len(p.eps) > 1 {
+		i := grpcrand.Intn(len(p.eps))
+		j := grpcrand.Intn(len(p.eps) - 1)
+		if j >= i {
+			j++
+		}
+		a, b := p.eps[i], p.eps[j]
+		chosen = a
+		if b.stats.cost() < a.stats.cost() {
+			chosen = b
+		}
+	}
+
+	started := chosen.stats.start()
+	return balancer.PickResult{
+		SubConn: chosen.sc,
+		Done: func(balancer.DoneInfo) {
+			chosen.stats.finish(started)
+		},
+	}, nil
+}
@@ -0,0 +1,72 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package p2c
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// epStats tracks one endpoint's in-flight RPC count and an EWMA of its RPC
+// latency. It lives on the balancer (on the endpoint, keyed off
+// apis.SubConn), not on the picker, because a new Picker is built on every
+// SubConn state change and the stats must survive those rebuilds.
+type epStats struct {
+	mu         sync.Mutex
+	inFlight   int64
+	ewma       float64 // latency EWMA, in nanoseconds
+	lastUpdate time.Time
+	halfLife   time.Duration
+}
+
+// cost is Finagle's "least-loaded" heuristic: in-flight RPCs times the
+// latency EWMA. An endpoint with no completed RPCs yet has a zero EWMA, so
+// it is preferred over a known-slow endpoint until it has seen traffic.
+func (s *epStats) cost() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return float64(s.inFlight) * s.ewma
+}
+
+// start records a new in-flight RPC and returns the time it started, to be
+// passed to finish once the RPC completes.
+func (s *epStats) start() time.Time {
+	s.mu.Lock()
+	s.inFlight++
+	s.mu.Unlock()
+	return time.Now()
+}
+
+// finish records an RPC's completion, decaying the existing latency EWMA
+// by elapsed-time-over-halfLife and blending in the new sample.
+func (s *epStats) finish(started time.Time) {
+	latency := float64(time.Since(started))
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inFlight--
+	decay := 0.0
+	if !s.lastUpdate.IsZero() && s.halfLife > 0 {
+		decay = math.Pow(0.5, float64(now.Sub(s.lastUpdate))/float64(s.halfLife))
+	}
+	s.ewma = decay*s.ewma + (1-decay)*latency
+	s.lastUpdate = now
+}
@@ -0,0 +1,63 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package p2c
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEpStats_CostReflectsInFlightAndLatency(t *testing.T) {
+	s := &epStats{halfLife: time.Second}
+
+	if got := s.cost(); got != 0 {
+		t.Fatalf("cost() of an unused endpoint = %v, want 0", got)
+	}
+
+	started := s.start()
+	if s.cost() != 0 {
+		t.Errorf("cost() before any completed RPC should still be 0 (no latency sample yet)")
+	}
+	time.Sleep(time.Millisecond)
+	s.finish(started)
+
+	if s.cost() != 0 {
+		t.Errorf("cost() with zero in-flight RPCs = %v, want 0", s.cost())
+	}
+
+	s.start()
+	if s.cost() <= 0 {
+		t.Errorf("cost() with an in-flight RPC and a known latency sample should be > 0")
+	}
+}
+
+func TestEpStats_DecayForgetsOldSamples(t *testing.T) {
+	s := &epStats{halfLife: time.Millisecond}
+	s.start()
+	s.finish(time.Now().Add(-time.Millisecond)) // seeds a large latency sample
+	firstEWMA := s.ewma
+
+	time.Sleep(10 * time.Millisecond)
+	s.start()
+	s.finish(time.Now()) // near-zero latency sample, long after the first
+
+	if s.ewma >= firstEWMA {
+		t.Errorf("ewma did not decay toward the new low-latency sample: got %v, want < %v", s.ewma, firstEWMA)
+	}
+}
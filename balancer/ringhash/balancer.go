@@ -0,0 +1,137 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package ringhash
+
+import (
+	"fmt"
+	"sync"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/apis"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/internal/grpcrand"
+	"google.golang.org/grpc/resolver"
+)
+
+// endpoint is the ring_hash view of a single resolved address: the SubConn
+// gRPC created for it, its current connectivity state (tracked here
+// because the ring, unlike base's PickerBuildInfo, needs not-Ready
+// endpoints too, so a picker can walk past them), and its ring weight.
+type endpoint struct {
+	addr   resolver.Address
+	sc     apis.SubConn
+	state  connectivity.State
+	weight uint64
+}
+
+type ringhashBalancer struct {
+	cc balancer.ClientConn
+
+	mu          sync.Mutex
+	cfg         LBConfig
+	csEvaluator balancer.ConnectivityStateEvaluator
+	state       connectivity.State
+	subConns    map[resolver.Address]*endpoint
+	scStates    map[apis.SubConn]*endpoint
+}
+
+func (b *ringhashBalancer) UpdateClientConnState(s balancer.ClientConnState) error {
+	cfg, ok := s.BalancerConfig.(LBConfig)
+	if !ok {
+		return fmt.Errorf("ring_hash: received unexpected balancer config type: %T", s.BalancerConfig)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cfg = cfg
+
+	addrsSet := make(map[resolver.Address]struct{}, len(s.ResolverState.Addresses))
+	for _, a := range s.ResolverState.Addresses {
+		addrsSet[a] = struct{}{}
+		if _, ok := b.subConns[a]; ok {
+			continue
+		}
+		sc, err := b.cc.NewSubConn([]resolver.Address{a}, balancer.NewSubConnOptions{})
+		if err != nil {
+			logger.Warningf("ring_hash: failed to create new SubConn for %v: %v", a, err)
+			continue
+		}
+		ep := &endpoint{addr: a, sc: sc, state: connectivity.Idle, weight: 1}
+		b.subConns[a] = ep
+		b.scStates[sc] = ep
+	}
+	for a, ep := range b.subConns {
+		if _, ok := addrsSet[a]; !ok {
+			b.cc.RemoveSubConn(ep.sc)
+			delete(b.subConns, a)
+		}
+	}
+
+	b.regeneratePickerLocked()
+	// Start the first connection attempt for every new endpoint now that
+	// the ring (and thus the set of endpoints a picker may nudge) reflects
+	// them.
+	for _, ep := range b.subConns {
+		if ep.state == connectivity.Idle {
+			ep.sc.Connect()
+		}
+	}
+	return nil
+}
+
+func (b *ringhashBalancer) ResolverError(err error) {
+	logger.Warningf("ring_hash: resolver error: %v", err)
+}
+
+func (b *ringhashBalancer) UpdateSubConnState(sc apis.SubConn, s balancer.SubConnState) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ep, ok := b.scStates[sc]
+	if !ok {
+		return
+	}
+	oldState := ep.state
+	if s.ConnectivityState == connectivity.Shutdown {
+		delete(b.scStates, sc)
+	} else {
+		ep.state = s.ConnectivityState
+	}
+	b.state = b.csEvaluator.RecordTransition(oldState, s.ConnectivityState)
+	b.regeneratePickerLocked()
+}
+
+func (b *ringhashBalancer) regeneratePickerLocked() {
+	if len(b.subConns) == 0 {
+		b.cc.UpdateState(balancer.State{ConnectivityState: b.state, Picker: base.NewErrPicker(balancer.ErrNoSubConnAvailable)})
+		return
+	}
+	eps := make([]*endpoint, 0, len(b.subConns))
+	for _, ep := range b.subConns {
+		eps = append(eps, ep)
+	}
+	p := &ringPicker{
+		r:           newRing(eps, b.cfg.MinRingSize, b.cfg.MaxRingSize),
+		hashPolicy:  b.cfg.HashPolicy,
+		fallbackKey: fmt.Sprintf("%d", grpcrand.Int63()),
+	}
+	b.cc.UpdateState(balancer.State{ConnectivityState: b.state, Picker: p})
+}
+
+func (b *ringhashBalancer) Close() {}
@@ -0,0 +1,118 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package ringhash
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/grpc/serviceconfig"
+)
+
+const (
+	defaultMinRingSize = 1024
+	defaultMaxRingSize = 4096
+
+	// ringSizeCap keeps a misconfigured service config from building a ring
+	// so large that it dominates balancer memory and rebuild time.
+	ringSizeCap = 8 * 1024 * 1024
+)
+
+// HashPolicy describes one entry of the hash_policy list: the request
+// attribute used to derive the hash key, and whether evaluation of the
+// list should stop here regardless of whether this entry produced a key.
+// This mirrors the sticky-metadata pattern customRoundrobin.OverWriteKeyName
+// uses for its "lb-addr" override, generalized to a configurable list of
+// candidate headers.
+type HashPolicy struct {
+	// HeaderName is the outgoing metadata key this policy reads its hash
+	// key from.
+	HeaderName string
+	// Terminal, if true, stops hash_policy evaluation after this entry even
+	// if HeaderName was not present in the request's metadata.
+	Terminal bool
+}
+
+// LBConfig is the balancer config for ring_hash.
+//
+// Each SubConn, Ready or not, is assigned weight * MinRingSize /
+// total_weight ring entries (rounded down, at least one; MaxRingSize is
+// used in place of MinRingSize when MinRingSize is smaller than
+// total_weight), placed at xxhash64("<address>#<index>"). Rounding down can
+// leave the ring up to one entry per SubConn short of that size. A Pick hashes its
+// key (derived from HashPolicy, or a random fallback) onto the ring and
+// walks forward from the first entry at or after that hash until it finds
+// a Ready SubConn.
+type LBConfig struct {
+	serviceconfig.LoadBalancingConfig
+
+	// MinRingSize and MaxRingSize bound the number of entries placed on the
+	// ring. Default to defaultMinRingSize and defaultMaxRingSize.
+	MinRingSize uint64
+	MaxRingSize uint64
+
+	// HashPolicy is the ordered list of candidate hash keys. The first
+	// entry whose HeaderName is present in the RPC's outgoing metadata
+	// wins, though entries after a Terminal entry are not evaluated; if
+	// none match, the picker falls back to a randomly generated key.
+	HashPolicy []HashPolicy
+}
+
+type hashPolicyJSON struct {
+	HeaderName string `json:"header,omitempty"`
+	Terminal   bool   `json:"terminal,omitempty"`
+}
+
+type lbConfigJSON struct {
+	MinRingSize uint64           `json:"minRingSize,omitempty"`
+	MaxRingSize uint64           `json:"maxRingSize,omitempty"`
+	HashPolicy  []hashPolicyJSON `json:"hashPolicy,omitempty"`
+}
+
+// ParseConfig parses the ring_hash JSON load balancing config, filling in
+// unset fields with their defaults.
+func (bb) ParseConfig(c json.RawMessage) (serviceconfig.LoadBalancingConfig, error) {
+	var cfgJSON lbConfigJSON
+	if err := json.Unmarshal(c, &cfgJSON); err != nil {
+		return nil, fmt.Errorf("ring_hash: unable to unmarshal LBConfig: %v", err)
+	}
+
+	cfg := LBConfig{
+		MinRingSize: defaultMinRingSize,
+		MaxRingSize: defaultMaxRingSize,
+	}
+	if cfgJSON.MinRingSize > 0 {
+		cfg.MinRingSize = cfgJSON.MinRingSize
+	}
+	if cfgJSON.MaxRingSize > 0 {
+		cfg.MaxRingSize = cfgJSON.MaxRingSize
+	}
+	if cfg.MinRingSize > cfg.MaxRingSize {
+		return nil, fmt.Errorf("ring_hash: minRingSize (%v) must be <= maxRingSize (%v)", cfg.MinRingSize, cfg.MaxRingSize)
+	}
+	if cfg.MaxRingSize > ringSizeCap {
+		return nil, fmt.Errorf("ring_hash: maxRingSize (%v) must be <= %v", cfg.MaxRingSize, ringSizeCap)
+	}
+
+	for _, hp := range cfgJSON.HashPolicy {
+		cfg.HashPolicy = append(cfg.HashPolicy, HashPolicy{HeaderName: hp.HeaderName, Terminal: hp.Terminal})
+	}
+
+	return cfg, nil
+}
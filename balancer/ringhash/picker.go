@@ -0,0 +1,113 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package ringhash
+
+import (
+	"context"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/metadata"
+)
+
+// ringPicker hashes each Pick's key onto r and walks forward from the
+// first virtual node at or after that hash until it finds a Ready
+// endpoint. Endpoints it passes over that are still Idle are nudged to
+// connect (only the first one, so a single Pick doesn't storm every
+// backend on the ring) so affinity converges once that endpoint comes up.
+type ringPicker struct {
+	r          *ring
+	hashPolicy []HashPolicy
+
+	// fallbackKey is used for every Pick whose hashPolicy doesn't match, so
+	// that unmatched RPCs keep the same ring affinity for as long as this
+	// picker is in effect, rather than scattering independently on every
+	// Pick. It's generated once per picker (i.e. on every SubConn state
+	// change that rebuilds the ring), which is as close to "per
+	// connection" as a Picker -- built before any SubConn is chosen -- can
+	// get.
+	fallbackKey string
+}
+
+func (p *ringPicker) Pick(info balancer.PickInfo) (balancer.PickResult, error) {
+	key, ok := p.hashKey(info.Ctx)
+	if !ok {
+		key = p.fallbackKey
+	}
+	h := xxhash64([]byte(key))
+
+	var result balancer.PickResult
+	found := false
+	connectedIdle := false
+	p.r.walk(h, func(ep *endpoint) bool {
+		switch ep.state {
+		case connectivity.Ready:
+			result = balancer.PickResult{SubConn: ep.sc}
+			found = true
+			return true
+		case connectivity.Idle:
+			if !connectedIdle {
+				ep.sc.Connect()
+				connectedIdle = true
+			}
+		}
+		return false
+	})
+	if !found {
+		return balancer.PickResult{}, balancer.ErrNoSubConnAvailable
+	}
+	return result, nil
+}
+
+// hashKey evaluates the configured HashPolicy list in order, returning the
+// first header value found. Matching customRoundrobin.stickyKeyFromContext,
+// it reads both the context's outgoing metadata and any metadata added via
+// metadata.AppendToOutgoingContext that hasn't been merged in yet.
+func (p *ringPicker) hashKey(ctx context.Context) (string, bool) {
+	for _, hp := range p.hashPolicy {
+		if v, ok := headerFromContext(ctx, hp.HeaderName); ok {
+			return v, true
+		}
+		if hp.Terminal {
+			break
+		}
+	}
+	return "", false
+}
+
+func headerFromContext(ctx context.Context, headerName string) (string, bool) {
+	if headerName == "" {
+		return "", false
+	}
+	md, added, ok := metadata.FromOutgoingContextRaw(ctx)
+	if !ok {
+		return "", false
+	}
+	if vv, ok := md[headerName]; ok && len(vv) > 0 {
+		return vv[0], true
+	}
+	for _, ss := range added {
+		for i := 0; i < len(ss)-1; i += 2 {
+			if ss[i] == headerName {
+				return ss[i+1], true
+			}
+		}
+	}
+	return "", false
+}
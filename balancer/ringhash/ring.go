@@ -0,0 +1,94 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package ringhash
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ringEntry is one virtual node on the consistent hash ring.
+type ringEntry struct {
+	hash uint64
+	ep   *endpoint
+}
+
+// ring is an immutable, hash-sorted set of virtual nodes built from the
+// balancer's current endpoints. A new one is built (via newRing) for every
+// picker the balancer generates, i.e. on every resolver update and every
+// SubConn state change.
+type ring struct {
+	entries []ringEntry
+}
+
+// newRing builds a Ketama-style ring from eps: each endpoint is given
+// weight * minRingSize / totalWeight virtual nodes (rounded down, at least
+// one), hashed as xxhash64("<addr>#<index>"). Rounding down can leave the
+// ring up to one entry per endpoint short of minRingSize, and because
+// every endpoint gets at least one node, the ring holds more than
+// maxRingSize entries when there are more endpoints than that.
+func newRing(eps []*endpoint, minRingSize, maxRingSize uint64) *ring {
+	if len(eps) == 0 {
+		return &ring{}
+	}
+
+	var totalWeight uint64
+	for _, ep := range eps {
+		totalWeight += ep.weight
+	}
+
+	// minRingSize virtual nodes spread across totalWeight units of weight;
+	// scale up (without exceeding maxRingSize) if that would otherwise
+	// round endpoints down to zero nodes.
+	ringSize := minRingSize
+	if ringSize/totalWeight == 0 && maxRingSize/totalWeight > 0 {
+		ringSize = maxRingSize
+	}
+
+	entries := make([]ringEntry, 0, ringSize)
+	for _, ep := range eps {
+		num := ep.weight * ringSize / totalWeight
+		if num == 0 {
+			num = 1
+		}
+		for i := uint64(0); i < num; i++ {
+			h := xxhash64([]byte(fmt.Sprintf("%s#%d", ep.addr.Addr, i)))
+			entries = append(entries, ringEntry{hash: h, ep: ep})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].hash < entries[j].hash })
+	return &ring{entries: entries}
+}
+
+// walk calls f with every endpoint on the ring, in order starting at the
+// first entry whose hash is >= h (wrapping around if none is), stopping
+// early if f returns true. It does not allocate: this is the per-Pick hot
+// path, and the common case (the first candidate is Ready) must not pay
+// for materializing the whole ring.
+func (r *ring) walk(h uint64, f func(*endpoint) (stop bool)) {
+	if len(r.entries) == 0 {
+		return
+	}
+	start := sort.Search(len(r.entries), func(i int) bool { return r.entries[i].hash >= h })
+	for i := 0; i < len(r.entries); i++ {
+		if f(r.entries[(start+i)%len(r.entries)].ep) {
+			return
+		}
+	}
+}
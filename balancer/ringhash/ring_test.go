@@ -0,0 +1,102 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package ringhash
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/attributes"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/resolver"
+)
+
+// fakeSubConn is a bare-bones apis.SubConn, distinguished by pointer
+// identity, so endpoints can be exercised without gRPC's connection
+// internals. It carries an unused field so instances aren't zero-sized:
+// Go collapses every *T for a zero-size T onto the same runtime.zerobase
+// address once boxed into an interface, which would make every
+// fakeSubConn compare equal.
+type fakeSubConn struct{ _ int }
+
+func (*fakeSubConn) UpdateAddresses([]resolver.Address) {}
+func (*fakeSubConn) Connect()                           {}
+func (*fakeSubConn) Attributes() *attributes.Attributes { return nil }
+
+func newTestEndpoint(addr string, state connectivity.State) *endpoint {
+	return &endpoint{addr: resolver.Address{Addr: addr}, sc: &fakeSubConn{}, state: state, weight: 1}
+}
+
+func TestNewRing_SortedAndSized(t *testing.T) {
+	eps := []*endpoint{
+		newTestEndpoint("1.1.1.1:80", connectivity.Ready),
+		newTestEndpoint("2.2.2.2:80", connectivity.Ready),
+		newTestEndpoint("3.3.3.3:80", connectivity.Ready),
+	}
+	r := newRing(eps, 1024, 4096)
+
+	// Per-endpoint virtual node counts are floor(weight*ringSize/totalWeight),
+	// so the total can fall a few entries short of minRingSize (at most
+	// one short per endpoint) rather than hit it exactly.
+	if len(r.entries) < 1024-len(eps) {
+		t.Fatalf("ring has %d entries, want at least ~the configured minRingSize 1024", len(r.entries))
+	}
+	for i := 1; i < len(r.entries); i++ {
+		if r.entries[i].hash < r.entries[i-1].hash {
+			t.Fatalf("ring entries not sorted by hash at index %d", i)
+		}
+	}
+}
+
+func TestRing_WalkWrapsAround(t *testing.T) {
+	eps := []*endpoint{
+		newTestEndpoint("1.1.1.1:80", connectivity.Ready),
+		newTestEndpoint("2.2.2.2:80", connectivity.Ready),
+	}
+	r := newRing(eps, 1024, 4096)
+
+	// A hash past every entry must wrap back to the first one: walk should
+	// still visit every entry exactly once before stopping.
+	var visited int
+	r.walk(^uint64(0), func(*endpoint) bool {
+		visited++
+		return false
+	})
+	if visited != len(r.entries) {
+		t.Fatalf("walk() visited %d endpoints, want %d", visited, len(r.entries))
+	}
+}
+
+func TestRingPicker_SkipsNonReady(t *testing.T) {
+	idle := newTestEndpoint("1.1.1.1:80", connectivity.Idle)
+	ready := newTestEndpoint("2.2.2.2:80", connectivity.Ready)
+	r := &ring{entries: []ringEntry{
+		{hash: 0, ep: idle},
+		{hash: 1, ep: ready},
+	}}
+	p := &ringPicker{r: r}
+
+	res, err := p.Pick(balancer.PickInfo{})
+	if err != nil {
+		t.Fatalf("Pick() returned unexpected error: %v", err)
+	}
+	if res.SubConn != ready.sc {
+		t.Errorf("Pick() chose the Idle endpoint instead of walking forward to the Ready one")
+	}
+}
@@ -0,0 +1,55 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package ringhash provides an implementation of the ring hash LB policy,
+// which selects a SubConn by hashing a request key onto a Ketama-style
+// consistent hash ring built from the ready (and not-yet-ready) SubConns.
+//
+// All APIs in this package are experimental.
+package ringhash
+
+import (
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/apis"
+	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/resolver"
+)
+
+// Name is the name of the ring_hash balancer, as registered via
+// balancer.Register.
+const Name = "ring_hash"
+
+var logger = grpclog.Component("ring-hash")
+
+func init() {
+	balancer.Register(bb{})
+}
+
+type bb struct{}
+
+func (bb) Name() string { return Name }
+
+func (bb) Build(cc balancer.ClientConn, opts balancer.BuildOptions) balancer.Balancer {
+	return &ringhashBalancer{
+		cc:       cc,
+		subConns: make(map[resolver.Address]*endpoint),
+		scStates: make(map[apis.SubConn]*endpoint),
+	}
+}
+
+var _ balancer.ConfigParser = bb{}
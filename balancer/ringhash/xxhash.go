@@ -0,0 +1,117 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package ringhash
+
+// xxhash64 is a small, self-contained implementation of the xxHash64
+// algorithm (seed 0), used to place ring entries and to hash Pick keys.
+// It exists here rather than as an external dependency because nothing
+// else in this module needs a general-purpose hashing package.
+const (
+	prime64_1 uint64 = 11400714785074694791
+	prime64_2 uint64 = 14029467366897019727
+	prime64_3 uint64 = 1609587929392839161
+	prime64_4 uint64 = 9650029242287828579
+	prime64_5 uint64 = 2870177450012600261
+)
+
+func xxhash64(data []byte) uint64 {
+	var h64 uint64
+	n := len(data)
+
+	if n >= 32 {
+		// v1 and v4 are computed at runtime, not as constant expressions:
+		// prime64_1+prime64_2 and -prime64_1 both overflow uint64 and the
+		// Go compiler rejects that in a constant-folded expression even
+		// though the intended semantics (wraparound) are well-defined for
+		// a runtime uint64 value.
+		var v1 uint64 = prime64_1
+		v1 += prime64_2
+		v2 := prime64_2
+		v3 := uint64(0)
+		var v4 uint64
+		v4 -= prime64_1
+
+		for len(data) >= 32 {
+			v1 = xxround64(v1, le64(data[0:8]))
+			v2 = xxround64(v2, le64(data[8:16]))
+			v3 = xxround64(v3, le64(data[16:24]))
+			v4 = xxround64(v4, le64(data[24:32]))
+			data = data[32:]
+		}
+
+		h64 = rotl64(v1, 1) + rotl64(v2, 7) + rotl64(v3, 12) + rotl64(v4, 18)
+		h64 = xxmergeRound64(h64, v1)
+		h64 = xxmergeRound64(h64, v2)
+		h64 = xxmergeRound64(h64, v3)
+		h64 = xxmergeRound64(h64, v4)
+	} else {
+		h64 = prime64_5
+	}
+
+	h64 += uint64(n)
+
+	for len(data) >= 8 {
+		h64 ^= xxround64(0, le64(data[0:8]))
+		h64 = rotl64(h64, 27)*prime64_1 + prime64_4
+		data = data[8:]
+	}
+	if len(data) >= 4 {
+		h64 ^= uint64(le32(data[0:4])) * prime64_1
+		h64 = rotl64(h64, 23)*prime64_2 + prime64_3
+		data = data[4:]
+	}
+	for len(data) > 0 {
+		h64 ^= uint64(data[0]) * prime64_5
+		h64 = rotl64(h64, 11) * prime64_1
+		data = data[1:]
+	}
+
+	h64 ^= h64 >> 33
+	h64 *= prime64_2
+	h64 ^= h64 >> 29
+	h64 *= prime64_3
+	h64 ^= h64 >> 32
+
+	return h64
+}
+
+func xxround64(acc, input uint64) uint64 {
+	acc += input * prime64_2
+	acc = rotl64(acc, 31)
+	return acc * prime64_1
+}
+
+func xxmergeRound64(acc, val uint64) uint64 {
+	val = xxround64(0, val)
+	acc ^= val
+	return acc*prime64_1 + prime64_4
+}
+
+func rotl64(x uint64, r uint) uint64 {
+	return (x << r) | (x >> (64 - r))
+}
+
+func le64(b []byte) uint64 {
+	return uint64(b[0]) | uint64(b[1])<<8 | uint64(b[2])<<16 | uint64(b[3])<<24 |
+		uint64(b[4])<<32 | uint64(b[5])<<40 | uint64(b[6])<<48 | uint64(b[7])<<56
+}
+
+func le32(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
+}
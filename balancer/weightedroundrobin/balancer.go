@@ -0,0 +1,231 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package weightedroundrobin
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	v3orcapb "github.com/cncf/xds/go/xds/data/orca/v3"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/apis"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/orca"
+	"google.golang.org/grpc/resolver"
+)
+
+type wrrBalancer struct {
+	cc balancer.ClientConn
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	refreshOnce sync.Once
+
+	mu          sync.Mutex
+	cfg         LBConfig
+	csEvaluator balancer.ConnectivityStateEvaluator
+	state       connectivity.State
+	subConns    map[resolver.Address]*endpointWeight
+	scStates    map[apis.SubConn]*endpointWeight
+}
+
+func (b *wrrBalancer) UpdateClientConnState(s balancer.ClientConnState) error {
+	cfg, ok := s.BalancerConfig.(LBConfig)
+	if !ok {
+		return fmt.Errorf("wrr: received unexpected balancer config type: %T", s.BalancerConfig)
+	}
+
+	// Periodically regenerate the picker so that stale weights decay back
+	// to plain round robin (endpointWeight.weight enforces the TTL) even
+	// for an endpoint that receives no RPCs and has OOB reporting
+	// disabled.
+	b.refreshOnce.Do(func() {
+		go periodicWeightRefresh(b.ctx, cfg.WeightExpirationPeriod/2, func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			b.regeneratePickerLocked()
+		})
+	})
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cfg = cfg
+
+	addrsSet := make(map[resolver.Address]struct{}, len(s.ResolverState.Addresses))
+	for _, a := range s.ResolverState.Addresses {
+		addrsSet[a] = struct{}{}
+		if _, ok := b.subConns[a]; ok {
+			continue
+		}
+		ew := &endpointWeight{}
+		sc, err := b.cc.NewSubConn([]resolver.Address{a}, balancer.NewSubConnOptions{})
+		if err != nil {
+			logger.Warningf("wrr: failed to create new SubConn for %v: %v", a, err)
+			continue
+		}
+		ew.sc = sc
+		b.subConns[a] = ew
+		b.scStates[sc] = ew
+		sc.Connect()
+	}
+	for a, ew := range b.subConns {
+		if _, ok := addrsSet[a]; !ok {
+			b.cc.RemoveSubConn(ew.sc)
+			delete(b.subConns, a)
+		}
+	}
+	b.regeneratePickerLocked()
+	return nil
+}
+
+func (b *wrrBalancer) ResolverError(err error) {
+	logger.Warningf("wrr: resolver error: %v", err)
+}
+
+func (b *wrrBalancer) UpdateSubConnState(sc apis.SubConn, s balancer.SubConnState) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ew, ok := b.scStates[sc]
+	if !ok {
+		return
+	}
+	oldState := ew.connState
+	if s.ConnectivityState == connectivity.Shutdown {
+		delete(b.scStates, sc)
+	} else {
+		ew.connState = s.ConnectivityState
+	}
+	b.state = b.csEvaluator.RecordTransition(oldState, s.ConnectivityState)
+
+	if b.cfg.EnableOOBLoadReport {
+		if s.ConnectivityState == connectivity.Ready {
+			if lc, ok := sc.(orca.OOBListenerConn); ok && ew.stopOOB == nil {
+				ew.stopOOB = startOOBWatch(ew, lc, b.cfg)
+			}
+		} else if ew.stopOOB != nil {
+			ew.stopOOB()
+			ew.stopOOB = nil
+		}
+	}
+
+	b.regeneratePickerLocked()
+}
+
+func (b *wrrBalancer) regeneratePickerLocked() {
+	var weights []endpointWeightSnapshot
+	for _, ew := range b.subConns {
+		if ew.connState != connectivity.Ready {
+			continue
+		}
+		weights = append(weights, endpointWeightSnapshot{
+			sc:     ew.sc,
+			weight: ew.weight(b.cfg),
+			ew:     ew,
+			cfg:    b.cfg,
+		})
+	}
+	var p balancer.Picker
+	if len(weights) == 0 {
+		p = base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	} else {
+		p = &wrrPicker{
+			scWeights: weights,
+			currents:  make([]float64, len(weights)),
+		}
+	}
+	b.cc.UpdateState(balancer.State{ConnectivityState: b.state, Picker: p})
+}
+
+func (b *wrrBalancer) Close() {
+	b.cancel()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, ew := range b.subConns {
+		if ew.stopOOB != nil {
+			ew.stopOOB()
+		}
+	}
+}
+
+// endpointWeight tracks the ORCA-derived weight of a single SubConn across
+// picker rebuilds (pickers are immutable snapshots, so the live EWMA must
+// live here, not on the picker).
+type endpointWeight struct {
+	sc        apis.SubConn
+	connState connectivity.State
+	stopOOB   func()
+
+	mu          sync.Mutex
+	ewma        float64
+	lastUpdated time.Time
+}
+
+// onLoadReport records a new utilization sample, updating the EWMA of the
+// endpoint's weight. util is the highest of the reported CPU/memory
+// utilization and any application-defined utilization metrics.
+func (ew *endpointWeight) onLoadReport(util float64, cfg LBConfig) {
+	w := 1 / math.Max(util, cfg.UtilizationFloor)
+	if w < cfg.MinWeight {
+		w = cfg.MinWeight
+	}
+	if w > cfg.MaxWeight {
+		w = cfg.MaxWeight
+	}
+
+	const decayFactor = 0.5 // EWMA weight given to the previous value
+	ew.mu.Lock()
+	defer ew.mu.Unlock()
+	if ew.lastUpdated.IsZero() {
+		ew.ewma = w
+	} else {
+		ew.ewma = decayFactor*ew.ewma + (1-decayFactor)*w
+	}
+	ew.lastUpdated = time.Now()
+}
+
+// weight returns the endpoint's current weight, or 1 (plain round robin) if
+// no load report has ever been observed or the last one is stale.
+func (ew *endpointWeight) weight(cfg LBConfig) float64 {
+	ew.mu.Lock()
+	defer ew.mu.Unlock()
+	if ew.lastUpdated.IsZero() || time.Since(ew.lastUpdated) > cfg.WeightExpirationPeriod {
+		return 1
+	}
+	return ew.ewma
+}
+
+// utilizationFromReport extracts the dominant utilization signal from an
+// ORCA load report: the max of CPU/memory utilization and any
+// application-reported utilization metrics, so a backend can advertise a
+// custom cost (e.g. queue depth) even without CPU pressure.
+func utilizationFromReport(r *v3orcapb.OrcaLoadReport) float64 {
+	util := math.Max(r.GetCpuUtilization(), r.GetMemUtilization())
+	for _, v := range r.GetUtilization() {
+		if v > util {
+			util = v
+		}
+	}
+	return util
+}
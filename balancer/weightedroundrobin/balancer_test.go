@@ -0,0 +1,153 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package weightedroundrobin
+
+import (
+	"testing"
+	"time"
+
+	v3orcapb "github.com/cncf/xds/go/xds/data/orca/v3"
+
+	"google.golang.org/grpc/attributes"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/apis"
+	"google.golang.org/grpc/resolver"
+)
+
+// fakeSubConn is a bare-bones apis.SubConn, distinguished by pointer
+// identity. It carries an unused field so instances aren't zero-sized: Go
+// collapses every *T for a zero-size T onto the same runtime.zerobase
+// address once boxed into an interface, which would make every fakeSubConn
+// compare equal.
+type fakeSubConn struct{ _ int }
+
+func (*fakeSubConn) UpdateAddresses([]resolver.Address) {}
+func (*fakeSubConn) Connect()                           {}
+func (*fakeSubConn) Attributes() *attributes.Attributes { return nil }
+
+// TestWRRPicker_DistributesByWeight drives enough Picks that the Smooth
+// Weighted Round Robin sequence should settle into picking each endpoint
+// proportionally to its weight, never starving the lightest one.
+func TestWRRPicker_DistributesByWeight(t *testing.T) {
+	sc1, sc2, sc3 := &fakeSubConn{}, &fakeSubConn{}, &fakeSubConn{}
+	p := &wrrPicker{
+		scWeights: []endpointWeightSnapshot{
+			{sc: sc1, weight: 3},
+			{sc: sc2, weight: 2},
+			{sc: sc3, weight: 1},
+		},
+		currents: make([]float64, 3),
+	}
+
+	counts := map[apis.SubConn]int{}
+	const n = 600
+	for i := 0; i < n; i++ {
+		res, err := p.Pick(balancer.PickInfo{})
+		if err != nil {
+			t.Fatalf("Pick() returned unexpected error: %v", err)
+		}
+		counts[res.SubConn]++
+	}
+
+	// Over many picks, SWRR converges to picking each endpoint in
+	// proportion to its weight (3:2:1 here, i.e. half, a third, and a
+	// sixth of all picks).
+	want := map[apis.SubConn]int{sc1: n * 3 / 6, sc2: n * 2 / 6, sc3: n * 1 / 6}
+	for sc, w := range want {
+		if got := counts[sc]; got < w-n/20 || got > w+n/20 {
+			t.Errorf("Pick() chose %p %d times, want ~%d (+/- %d)", sc, got, w, n/20)
+		}
+	}
+}
+
+// TestWRRPicker_DonePropagatesLoadReport verifies that a PickResult.Done
+// callback extracts an ORCA load report from DoneInfo.ServerLoad and feeds
+// it into the chosen endpoint's weight, so in-band reports (carried on the
+// RPC itself) update the live weight just like an out-of-band one would.
+func TestWRRPicker_DonePropagatesLoadReport(t *testing.T) {
+	ew := &endpointWeight{}
+	cfg := LBConfig{UtilizationFloor: 0.1, MinWeight: 1.0 / 100, MaxWeight: 100, WeightExpirationPeriod: time.Minute}
+	p := &wrrPicker{
+		scWeights: []endpointWeightSnapshot{{sc: &fakeSubConn{}, weight: 1, ew: ew, cfg: cfg}},
+		currents:  make([]float64, 1),
+	}
+
+	res, err := p.Pick(balancer.PickInfo{})
+	if err != nil {
+		t.Fatalf("Pick() returned unexpected error: %v", err)
+	}
+	res.Done(balancer.DoneInfo{ServerLoad: &v3orcapb.OrcaLoadReport{CpuUtilization: 0.5}})
+
+	if got, want := ew.weight(cfg), 1/0.5; got != want {
+		t.Errorf("weight() after a Done load report = %v, want %v", got, want)
+	}
+}
+
+func TestEndpointWeight_OnLoadReportClampsToConfiguredRange(t *testing.T) {
+	cfg := LBConfig{UtilizationFloor: 0.1, MinWeight: 0.5, MaxWeight: 2, WeightExpirationPeriod: time.Minute}
+	ew := &endpointWeight{}
+
+	ew.onLoadReport(10, cfg) // 1/10 = 0.1, below MinWeight
+	if got := ew.weight(cfg); got != cfg.MinWeight {
+		t.Errorf("weight() = %v, want MinWeight %v", got, cfg.MinWeight)
+	}
+
+	ew2 := &endpointWeight{}
+	ew2.onLoadReport(0.01, cfg) // 1/max(0.01, floor 0.1) = 10, above MaxWeight
+	if got := ew2.weight(cfg); got != cfg.MaxWeight {
+		t.Errorf("weight() = %v, want MaxWeight %v", got, cfg.MaxWeight)
+	}
+}
+
+func TestEndpointWeight_OnLoadReportDecaysTowardNewSamples(t *testing.T) {
+	cfg := LBConfig{UtilizationFloor: 0.1, MinWeight: 1.0 / 100, MaxWeight: 100, WeightExpirationPeriod: time.Minute}
+	ew := &endpointWeight{}
+
+	ew.onLoadReport(1, cfg) // weight 1
+	first := ew.weight(cfg)
+
+	ew.onLoadReport(0.1, cfg) // weight 10: EWMA should move partway there, not jump all the way
+	second := ew.weight(cfg)
+
+	if second <= first || second >= 10 {
+		t.Errorf("weight() after a second sample = %v, want strictly between %v and 10", second, first)
+	}
+}
+
+// TestEndpointWeight_WeightFallsBackToOneWhenUnreportedOrStale covers both
+// halves of the TTL contract: no report at all, and a report old enough to
+// have expired.
+func TestEndpointWeight_WeightFallsBackToOneWhenUnreportedOrStale(t *testing.T) {
+	cfg := LBConfig{UtilizationFloor: 0.1, MinWeight: 1.0 / 100, MaxWeight: 100, WeightExpirationPeriod: 10 * time.Millisecond}
+
+	ew := &endpointWeight{}
+	if got := ew.weight(cfg); got != 1 {
+		t.Errorf("weight() with no load report ever observed = %v, want 1", got)
+	}
+
+	ew.onLoadReport(0.5, cfg) // weight 2
+	if got := ew.weight(cfg); got != 2 {
+		t.Fatalf("weight() immediately after a load report = %v, want 2", got)
+	}
+
+	time.Sleep(2 * cfg.WeightExpirationPeriod)
+	if got := ew.weight(cfg); got != 1 {
+		t.Errorf("weight() after WeightExpirationPeriod elapsed = %v, want 1 (fallback to round robin)", got)
+	}
+}
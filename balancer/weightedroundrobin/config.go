@@ -0,0 +1,130 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package weightedroundrobin
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/serviceconfig"
+)
+
+const (
+	// defaultUtilizationFloor keeps a single idle/low-utilization backend
+	// from acquiring an unbounded weight.
+	defaultUtilizationFloor   = 0.1
+	defaultMinWeight          = 1.0 / 100
+	defaultMaxWeight          = 100
+	defaultWeightExpiration   = 3 * time.Minute
+	defaultOOBReportingPeriod = 10 * time.Second
+)
+
+// LBConfig is the balancer config for weighted_round_robin.
+//
+// Each ORCA load report yields a sample weight of
+// 1 / max(utilization_i, UtilizationFloor), clamped to [MinWeight,
+// MaxWeight]; weight_i is an EWMA of those samples, blending each new one
+// in equally with the previous value. Weights older than
+// WeightExpirationPeriod are treated as
+// unreported and the balancer falls back to plain round robin for that
+// endpoint.
+type LBConfig struct {
+	serviceconfig.LoadBalancingConfig
+
+	// UtilizationFloor is the minimum utilization value used when computing
+	// a weight, preventing division by (near) zero. Defaults to
+	// defaultUtilizationFloor.
+	UtilizationFloor float64 `json:"utilizationFloor,omitempty"`
+	// MinWeight and MaxWeight clamp the computed weight. Default to
+	// defaultMinWeight and defaultMaxWeight.
+	MinWeight float64 `json:"minWeight,omitempty"`
+	MaxWeight float64 `json:"maxWeight,omitempty"`
+	// WeightExpirationPeriod is the TTL of a reported weight. Defaults to
+	// defaultWeightExpiration.
+	WeightExpirationPeriod time.Duration `json:"-"`
+	// EnableOOBLoadReport, if true, opens a periodic out-of-band ORCA
+	// stream to each SubConn so utilization is refreshed even for
+	// endpoints that receive little or no traffic.
+	EnableOOBLoadReport bool `json:"enableOobLoadReport,omitempty"`
+	// OOBReportingPeriod controls how often the out-of-band report is
+	// polled when EnableOOBLoadReport is true. Defaults to
+	// defaultOOBReportingPeriod.
+	OOBReportingPeriod time.Duration `json:"-"`
+}
+
+// lbConfigJSON mirrors LBConfig, but with the time.Duration fields declared
+// as strings, matching the JSON representation of google.protobuf.Duration
+// used in service configs (e.g. "10s").
+type lbConfigJSON struct {
+	UtilizationFloor       float64 `json:"utilizationFloor,omitempty"`
+	MinWeight              float64 `json:"minWeight,omitempty"`
+	MaxWeight              float64 `json:"maxWeight,omitempty"`
+	WeightExpirationPeriod string  `json:"weightExpirationPeriod,omitempty"`
+	EnableOOBLoadReport    bool    `json:"enableOobLoadReport,omitempty"`
+	OOBReportingPeriod     string  `json:"oobReportingPeriod,omitempty"`
+}
+
+// ParseConfig parses the JSON load balancer config for weighted_round_robin
+// provided into an LBConfig, filling in unset fields with their defaults.
+func (bb) ParseConfig(c json.RawMessage) (serviceconfig.LoadBalancingConfig, error) {
+	var cfgJSON lbConfigJSON
+	if err := json.Unmarshal(c, &cfgJSON); err != nil {
+		return nil, fmt.Errorf("wrr: unable to unmarshal LBConfig: %v", err)
+	}
+
+	cfg := LBConfig{
+		UtilizationFloor:       defaultUtilizationFloor,
+		MinWeight:              defaultMinWeight,
+		MaxWeight:              defaultMaxWeight,
+		WeightExpirationPeriod: defaultWeightExpiration,
+		EnableOOBLoadReport:    cfgJSON.EnableOOBLoadReport,
+		OOBReportingPeriod:     defaultOOBReportingPeriod,
+	}
+	if cfgJSON.UtilizationFloor > 0 {
+		cfg.UtilizationFloor = cfgJSON.UtilizationFloor
+	}
+	if cfgJSON.MinWeight > 0 {
+		cfg.MinWeight = cfgJSON.MinWeight
+	}
+	if cfgJSON.MaxWeight > 0 {
+		cfg.MaxWeight = cfgJSON.MaxWeight
+	}
+	if cfgJSON.WeightExpirationPeriod != "" {
+		d, err := time.ParseDuration(cfgJSON.WeightExpirationPeriod)
+		if err != nil {
+			return nil, fmt.Errorf("wrr: invalid weightExpirationPeriod %q: %v", cfgJSON.WeightExpirationPeriod, err)
+		}
+		cfg.WeightExpirationPeriod = d
+	}
+	if cfg.WeightExpirationPeriod <= 0 {
+		return nil, fmt.Errorf("wrr: weightExpirationPeriod (%v) must be positive", cfg.WeightExpirationPeriod)
+	}
+	if cfgJSON.OOBReportingPeriod != "" {
+		d, err := time.ParseDuration(cfgJSON.OOBReportingPeriod)
+		if err != nil {
+			return nil, fmt.Errorf("wrr: invalid oobReportingPeriod %q: %v", cfgJSON.OOBReportingPeriod, err)
+		}
+		cfg.OOBReportingPeriod = d
+	}
+	if cfg.MinWeight > cfg.MaxWeight {
+		return nil, fmt.Errorf("wrr: minWeight (%v) must be <= maxWeight (%v)", cfg.MinWeight, cfg.MaxWeight)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,62 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package weightedroundrobin
+
+import (
+	"context"
+	"time"
+
+	v3orcapb "github.com/cncf/xds/go/xds/data/orca/v3"
+
+	"google.golang.org/grpc/orca"
+)
+
+// startOOBWatch opens an out-of-band ORCA stream to sc's backend and feeds
+// every report it receives into ew, so endpoints that receive little or no
+// RPC traffic still have their weight refreshed. It returns a func that
+// stops the stream; it is called from wrrBalancer.UpdateSubConnState once
+// the SubConn leaves Ready, and from Close.
+func startOOBWatch(ew *endpointWeight, cc orca.OOBListenerConn, cfg LBConfig) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		orca.WatchOOBLoadReport(ctx, cc, cfg.OOBReportingPeriod, func(r *v3orcapb.OrcaLoadReport) {
+			ew.onLoadReport(utilizationFromReport(r), cfg)
+		})
+	}()
+	return cancel
+}
+
+// periodicWeightRefresh is a fallback for SubConns not using the OOB
+// stream: it merely wakes up the balancer periodically so stale weights
+// decay back to plain round robin (endpointWeight.weight already handles
+// the TTL check; this just forces a new picker to be generated so the
+// decay is reflected even in the absence of new RPCs or SubConn state
+// changes).
+func periodicWeightRefresh(ctx context.Context, period time.Duration, f func()) {
+	t := time.NewTicker(period)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			f()
+		}
+	}
+}
@@ -0,0 +1,80 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package weightedroundrobin
+
+import (
+	"sync"
+
+	v3orcapb "github.com/cncf/xds/go/xds/data/orca/v3"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/apis"
+)
+
+// endpointWeightSnapshot is an immutable (SubConn, weight) pair captured
+// when a picker is built; the weight itself is read from the live
+// endpointWeight owned by the balancer.
+type endpointWeightSnapshot struct {
+	sc     apis.SubConn
+	weight float64
+	ew     *endpointWeight
+	cfg    LBConfig
+}
+
+// wrrPicker implements deterministic, starvation-free weighted round robin
+// using the Smooth Weighted Round Robin algorithm: on each pick, every
+// endpoint's "current" counter is incremented by its weight, the endpoint
+// with the largest counter is selected, and the sum of all weights is
+// subtracted from the winner's counter.
+type wrrPicker struct {
+	scWeights []endpointWeightSnapshot
+
+	mu       sync.Mutex
+	currents []float64
+}
+
+func (p *wrrPicker) Pick(info balancer.PickInfo) (balancer.PickResult, error) {
+	p.mu.Lock()
+	total := 0.0
+	best := 0
+	for i, sw := range p.scWeights {
+		p.currents[i] += sw.weight
+		total += sw.weight
+		if p.currents[i] > p.currents[best] {
+			best = i
+		}
+	}
+	p.currents[best] -= total
+	chosen := p.scWeights[best]
+	p.mu.Unlock()
+
+	return balancer.PickResult{
+		SubConn: chosen.sc,
+		Done: func(di balancer.DoneInfo) {
+			if chosen.ew == nil {
+				return
+			}
+			report, ok := di.ServerLoad.(*v3orcapb.OrcaLoadReport)
+			if !ok || report == nil {
+				return
+			}
+			chosen.ew.onLoadReport(utilizationFromReport(report), chosen.cfg)
+		},
+	}, nil
+}
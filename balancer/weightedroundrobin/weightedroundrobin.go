@@ -0,0 +1,61 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package weightedroundrobin provides an implementation of the weighted
+// round robin LB policy, which uses ORCA load reports carried in per-RPC
+// trailing metadata (balancer.DoneInfo.ServerLoad) to weight SubConns by
+// their reported utilization.
+//
+// All APIs in this package are experimental.
+package weightedroundrobin
+
+import (
+	"context"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/apis"
+	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/resolver"
+)
+
+// Name is the name of the weighted_round_robin balancer, as registered via
+// balancer.Register.
+const Name = "weighted_round_robin"
+
+var logger = grpclog.Component("weighted-round-robin")
+
+func init() {
+	balancer.Register(bb{})
+}
+
+type bb struct{}
+
+func (bb) Name() string { return Name }
+
+func (bb) Build(cc balancer.ClientConn, opts balancer.BuildOptions) balancer.Balancer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &wrrBalancer{
+		cc:       cc,
+		ctx:      ctx,
+		cancel:   cancel,
+		subConns: make(map[resolver.Address]*endpointWeight),
+		scStates: make(map[apis.SubConn]*endpointWeight),
+	}
+}
+
+var _ balancer.ConfigParser = bb{}
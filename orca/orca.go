@@ -0,0 +1,67 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package orca implements the client side of ORCA (Open Request Cost
+// Aggregation) out-of-band load reporting: a dedicated, periodic stream of
+// utilization reports from a backend, independent of the RPC request path.
+// It's consumed by load-balancing policies such as weighted_round_robin
+// that want a backend's weight to stay fresh even when it receives little
+// or no RPC traffic.
+package orca
+
+import (
+	"context"
+	"time"
+
+	v3orcapb "github.com/cncf/xds/go/xds/data/orca/v3"
+)
+
+// OOBListenerConn is implemented by a SubConn capable of opening a
+// dedicated out-of-band ORCA load-reporting stream to its backend. gRPC's
+// SubConn does not implement this today; it's the extension point a future
+// transport-level implementation can satisfy to let out-of-band reporting
+// activate.
+type OOBListenerConn interface {
+	// OpenOrcaStream asks the backend to start pushing an ORCA load report
+	// roughly every reportInterval, returning a channel of reports. The
+	// channel is closed once the stream ends, whether because ctx was
+	// canceled or the underlying connection was lost.
+	OpenOrcaStream(ctx context.Context, reportInterval time.Duration) (<-chan *v3orcapb.OrcaLoadReport, error)
+}
+
+// WatchOOBLoadReport opens an out-of-band ORCA stream on cc and invokes
+// onReport for every report it delivers, until ctx is canceled or the
+// stream ends. It returns once the stream can no longer deliver reports;
+// callers that want to retry should do so from their own ctx.
+func WatchOOBLoadReport(ctx context.Context, cc OOBListenerConn, reportInterval time.Duration, onReport func(*v3orcapb.OrcaLoadReport)) {
+	reports, err := cc.OpenOrcaStream(ctx, reportInterval)
+	if err != nil {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case r, ok := <-reports:
+			if !ok {
+				return
+			}
+			onReport(r)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+/*
+ *
+ * Copyright 2022 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package orca
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v3orcapb "github.com/cncf/xds/go/xds/data/orca/v3"
+)
+
+type fakeOOBListenerConn struct {
+	reports chan *v3orcapb.OrcaLoadReport
+	err     error
+}
+
+func (f *fakeOOBListenerConn) OpenOrcaStream(context.Context, time.Duration) (<-chan *v3orcapb.OrcaLoadReport, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.reports, nil
+}
+
+func TestWatchOOBLoadReport_DeliversReports(t *testing.T) {
+	reports := make(chan *v3orcapb.OrcaLoadReport, 1)
+	cc := &fakeOOBListenerConn{reports: reports}
+
+	got := make(chan *v3orcapb.OrcaLoadReport, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		WatchOOBLoadReport(ctx, cc, time.Second, func(r *v3orcapb.OrcaLoadReport) { got <- r })
+	}()
+
+	want := &v3orcapb.OrcaLoadReport{CpuUtilization: 0.5}
+	reports <- want
+	select {
+	case r := <-got:
+		if r != want {
+			t.Fatalf("onReport got %v, want %v", r, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for onReport")
+	}
+
+	close(reports)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("WatchOOBLoadReport did not return after its report channel closed")
+	}
+}
+
+func TestWatchOOBLoadReport_ReturnsOnOpenError(t *testing.T) {
+	cc := &fakeOOBListenerConn{err: errors.New("stream unavailable")}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		WatchOOBLoadReport(context.Background(), cc, time.Second, func(*v3orcapb.OrcaLoadReport) {
+			t.Error("onReport should not be called when OpenOrcaStream fails")
+		})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("WatchOOBLoadReport did not return after OpenOrcaStream failed")
+	}
+}
+
+func TestWatchOOBLoadReport_ReturnsOnContextCancel(t *testing.T) {
+	cc := &fakeOOBListenerConn{reports: make(chan *v3orcapb.OrcaLoadReport)}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		WatchOOBLoadReport(ctx, cc, time.Second, func(*v3orcapb.OrcaLoadReport) {})
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("WatchOOBLoadReport did not return after ctx was canceled")
+	}
+}